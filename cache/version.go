@@ -10,11 +10,43 @@ import (
 	"time"
 )
 
+// Set using -ldflags -X when built with a version stamp. See gopher.go.
+var version string
+
+/*
+Returns the version gopher was built with. Falls back to the module version, then for
+local builds to the commit recorded by the go command. Ex: (devel) 1a2b3c4d5e6f-dirty
+*/
 func Version() string {
-	if info, ok := debug.ReadBuildInfo(); ok {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(unknown)"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
 		return info.Main.Version
 	}
-	return "(unknown)"
+	devel := fmt.Sprintf("(devel) %.12s", revision)
+	if modified {
+		devel += "-dirty"
+	}
+	return devel
 }
 
 type CMD struct {
@@ -7,7 +7,6 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 
@@ -19,7 +18,9 @@ import (
 
 type RunCMD struct {
 	Target         string           `arg:"" default:"default" help:"Recipe to run."`
+	Args           []string         `arg:"" optional:"" passthrough:"all" help:"Arguments passed to the target (positional or as --flags) followed by any additional targets to run in order."`
 	List           bool             `short:"l" help:"List all targets then exit."`
+	KeepGoing      bool             `short:"k" help:"Run every target even if one fails, then report all failures."`
 	Compile        bool             `help:"Only run the gopher compile then exit without running the target."`
 	DisableHotswap bool             `help:"Disable restarting if the gopherfile changes while running."`
 	GoConfig       runtime.GoConfig `embed:"" group:"Golang Flags"`
@@ -70,11 +71,15 @@ func (config *RunCMD) run(ctx context.Context, stdout io.Writer, logger *slog.Lo
 	if config.List {
 		args = append(args, "-l")
 	}
+	if config.KeepGoing {
+		args = append(args, "--keep-going")
+	}
 	args = append(args, config.Target)
+	args = append(args, config.Args...)
 
 	path := filepath.Join(config.GopherDir, compile.BinaryName)
 
-	cmd := exec.CommandContext(ctx, path, args...)
+	cmd := runtime.CommandContext(ctx, path, args...)
 	cmd.Stdout = stdout
 	cmd.Stderr = stdout
 
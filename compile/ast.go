@@ -5,15 +5,48 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"maps"
+	"slices"
 	"strings"
+	"unicode"
 )
 
+/*
+Directive used in a target's doc comment to declare the targets it depends on.
+Dependencies are separated by spaces or commas and are run before the target
+in topological order, each at most once per invocation.
+
+	//gopher:deps Build Test
+*/
+const DepsDirective = "//gopher:deps"
+
 var aliases = map[string]string{
 	"*Gopher": "*runtime.Gopher",
 }
 
+/*
+Types a target may accept after its context.Context and *runtime.Gopher parameters.
+Values are parsed from the command line by the generated main.go.
+*/
+var ArgumentTypes = []string{
+	"string",
+	"bool",
+	"int",
+	"int64",
+	"uint",
+	"float64",
+	"time.Duration",
+}
+
+// A typed argument a target takes on the command line.
+type Parameter struct {
+	Name string
+	Type string
+}
+
 type funcSignature struct {
 	Name       string
+	Names      []string // Names of each parameter. Empty if the parameter is unnamed.
 	Parameters []string
 	Returns    []string
 }
@@ -34,6 +67,7 @@ func fromFuncDecl(fn *ast.FuncDecl) funcSignature {
 		}
 	}
 
+	names := []string{}
 	parameters := []string{}
 	if params := fn.Type.Params; params != nil {
 		for _, param := range params.List {
@@ -41,12 +75,20 @@ func fromFuncDecl(fn *ast.FuncDecl) funcSignature {
 			if alias, ok := aliases[t]; ok {
 				t = alias
 			}
-			parameters = append(parameters, t)
+			if len(param.Names) == 0 {
+				names = append(names, "")
+				parameters = append(parameters, t)
+			}
+			for _, name := range param.Names {
+				names = append(names, name.Name)
+				parameters = append(parameters, t)
+			}
 		}
 	}
 
 	signature := funcSignature{
 		Name:       fn.Name.String(),
+		Names:      names,
 		Parameters: parameters,
 		Returns:    returns,
 	}
@@ -70,28 +112,131 @@ func parseTargets(content []byte) ([]Target, []error, error) {
 		node, ok := decl.(*ast.FuncDecl)
 		if !ok || !node.Name.IsExported() {
 			continue
-		} else if err := isValidFunc(node); err != nil {
+		}
+		parameters, err := targetParameters(node)
+		if err != nil {
 			warnings = append(warnings, err)
 			continue
 		}
 
 		comment := "No target description provided."
+		var deps []string
 		if node.Doc != nil && len(node.Doc.List) > 0 {
 			comments := []string{}
 			for _, line := range node.Doc.List {
+				if names, ok := strings.CutPrefix(line.Text, DepsDirective); ok {
+					deps = append(deps, strings.FieldsFunc(names, isDepsSeparator)...)
+					continue
+				}
 				comments = append(comments, NormalizeComment(line.Text))
 			}
-			comment = strings.Join(comments, "\n")
+			if len(comments) > 0 {
+				comment = strings.TrimSpace(strings.Join(comments, "\n"))
+			}
 		}
 
 		targets = append(targets, Target{
-			Name:        node.Name.Name,
-			Description: comment,
+			Name:         node.Name.Name,
+			Description:  comment,
+			Dependencies: deps,
+			Parameters:   parameters,
 		})
 	}
+
+	if err := checkDependencies(targets); err != nil {
+		return nil, warnings, err
+	}
 	return targets, warnings, nil
 }
 
+func isDepsSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
+
+/*
+Ensures every dependency names a valid target that takes no arguments and that the
+dependency graph is acyclic.
+Target names are compared case-insensitively, matching how they are invoked.
+*/
+func checkDependencies(targets []Target) error {
+	names := map[string]string{}
+	dependencies := map[string][]string{}
+	parameters := map[string]int{}
+	for _, target := range targets {
+		names[strings.ToLower(target.Name)] = target.Name
+		dependencies[target.Name] = target.Dependencies
+		parameters[target.Name] = len(target.Parameters)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			start := slices.Index(path, name)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(path[start:], " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range dependencies[name] {
+			resolved, ok := names[strings.ToLower(dep)]
+			if !ok {
+				return fmt.Errorf("target %s: unknown dependency: %s", name, dep)
+			} else if parameters[resolved] > 0 {
+				return fmt.Errorf("target %s: dependency %s must not take arguments", name, resolved)
+			}
+			if err := visit(resolved, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	keys := slices.Sorted(maps.Keys(dependencies))
+	for _, name := range keys {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Validates the target's signature and returns any parameters it takes
+after the required context.Context and *runtime.Gopher.
+*/
+func targetParameters(fn *ast.FuncDecl) ([]Parameter, error) {
+	if err := isValidFunc(fn); err != nil {
+		return nil, err
+	}
+	signature := fromFuncDecl(fn)
+
+	var parameters []Parameter
+	for i := 2; i < len(signature.Parameters); i++ {
+		name, t := signature.Names[i], signature.Parameters[i]
+		if name == "" || name == "_" {
+			return nil, fmt.Errorf("%s: param %d: target arguments must be named", signature.Name, i)
+		} else if !slices.Contains(ArgumentTypes, t) {
+			return nil, fmt.Errorf("%s: param %d: unsupported argument type %s: want one of: %s",
+				signature.Name,
+				i,
+				t,
+				strings.Join(ArgumentTypes, ", "),
+			)
+		}
+		parameters = append(parameters, Parameter{Name: name, Type: t})
+	}
+	return parameters, nil
+}
+
 func isValidFunc(fn *ast.FuncDecl) error {
 	expected := funcSignature{
 		Name:       fn.Name.String(),
@@ -104,8 +249,8 @@ func isValidFunc(fn *ast.FuncDecl) error {
 
 	if fn.Type.TypeParams != nil && fn.Type.TypeParams.NumFields() != 0 {
 		return fmt.Errorf("expected 0 type parameters got: %d", fn.Type.TypeParams.NumFields())
-	} else if len(signature.Parameters) != len(expected.Parameters) {
-		return fmt.Errorf("expected %d parameters got: %d\n%w",
+	} else if len(signature.Parameters) < len(expected.Parameters) {
+		return fmt.Errorf("expected at least %d parameters got: %d\n%w",
 			len(expected.Parameters),
 			len(signature.Parameters),
 			errTail,
@@ -118,8 +263,8 @@ func isValidFunc(fn *ast.FuncDecl) error {
 		)
 	}
 
-	for i, param := range signature.Parameters {
-		expectedParam := expected.Parameters[i]
+	for i, expectedParam := range expected.Parameters {
+		param := signature.Parameters[i]
 		if param != expectedParam {
 			return fmt.Errorf("param %d: expected %s: got: %s\n%w", i, expectedParam, param, errTail)
 		}
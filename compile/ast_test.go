@@ -0,0 +1,174 @@
+package compile
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const header = `package main
+
+import "context"
+
+`
+
+func TestParseTargetsDependencies(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Source   string
+		Deps     map[string][]string
+		ErrorMsg string
+	}{
+		{
+			Name: "none",
+			Source: `
+// Builds.
+func Build(ctx context.Context, g *Gopher) error { return nil }
+`,
+			Deps: map[string][]string{"Build": nil},
+		},
+		{
+			Name: "chain",
+			Source: `
+func Build(ctx context.Context, g *Gopher) error { return nil }
+
+// Tests.
+//gopher:deps Build
+func Test(ctx context.Context, g *Gopher) error { return nil }
+
+//gopher:deps build, test
+func CICD(ctx context.Context, g *Gopher) error { return nil }
+`,
+			Deps: map[string][]string{
+				"Build": nil,
+				"Test":  {"Build"},
+				"CICD":  {"build", "test"},
+			},
+		},
+		{
+			Name: "unknown",
+			Source: `
+//gopher:deps Missing
+func Build(ctx context.Context, g *Gopher) error { return nil }
+`,
+			ErrorMsg: "unknown dependency: Missing",
+		},
+		{
+			Name: "self",
+			Source: `
+//gopher:deps Build
+func Build(ctx context.Context, g *Gopher) error { return nil }
+`,
+			ErrorMsg: "dependency cycle: Build -> Build",
+		},
+		{
+			Name: "arguments",
+			Source: `
+func Release(ctx context.Context, g *Gopher, version string) error { return nil }
+
+//gopher:deps Release
+func CICD(ctx context.Context, g *Gopher) error { return nil }
+`,
+			ErrorMsg: "dependency Release must not take arguments",
+		},
+		{
+			Name: "cycle",
+			Source: `
+//gopher:deps C
+func A(ctx context.Context, g *Gopher) error { return nil }
+
+//gopher:deps A
+func B(ctx context.Context, g *Gopher) error { return nil }
+
+//gopher:deps B
+func C(ctx context.Context, g *Gopher) error { return nil }
+`,
+			ErrorMsg: "dependency cycle: A -> C -> B -> A",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			targets, _, err := parseTargets([]byte(header + test.Source))
+			if test.ErrorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.ErrorMsg) {
+					t.Fatalf(`got error: "%v" expected: "%s"`, err, test.ErrorMsg)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("got error: %s", err.Error())
+			}
+
+			if len(targets) != len(test.Deps) {
+				t.Fatalf("got %d targets expected: %d", len(targets), len(test.Deps))
+			}
+			for _, target := range targets {
+				if expected := test.Deps[target.Name]; !slices.Equal(target.Dependencies, expected) {
+					t.Errorf("%s: got: %v expected: %v", target.Name, target.Dependencies, expected)
+				}
+				if strings.Contains(target.Description, DepsDirective) {
+					t.Errorf("%s: description contains directive: %q", target.Name, target.Description)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTargetsParameters(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Source     string
+		Parameters []Parameter
+		Warning    string
+	}{
+		{
+			Name:   "none",
+			Source: `func Build(ctx context.Context, g *Gopher) error { return nil }`,
+		},
+		{
+			Name:   "typed",
+			Source: `func Release(ctx context.Context, g *Gopher, version, tag string, dryRun bool, wait time.Duration) error { return nil }`,
+			Parameters: []Parameter{
+				{Name: "version", Type: "string"},
+				{Name: "tag", Type: "string"},
+				{Name: "dryRun", Type: "bool"},
+				{Name: "wait", Type: "time.Duration"},
+			},
+		},
+		{
+			Name:    "unsupported",
+			Source:  `func Release(ctx context.Context, g *Gopher, versions []string) error { return nil }`,
+			Warning: "unsupported argument type []string",
+		},
+		{
+			Name:    "unnamed",
+			Source:  `func Release(ctx context.Context, g *Gopher, _ string) error { return nil }`,
+			Warning: "target arguments must be named",
+		},
+		{
+			Name:    "missing gopher",
+			Source:  `func Release(ctx context.Context, version string) error { return nil }`,
+			Warning: "expected *runtime.Gopher",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			targets, warnings, err := parseTargets([]byte(header + test.Source))
+			if err != nil {
+				t.Fatalf("got error: %s", err.Error())
+			}
+			if test.Warning != "" {
+				if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), test.Warning) {
+					t.Fatalf(`got warnings: %v expected: "%s"`, warnings, test.Warning)
+				}
+				return
+			} else if len(warnings) != 0 || len(targets) != 1 {
+				t.Fatalf("got warnings: %v targets: %v", warnings, targets)
+			}
+			if !slices.Equal(targets[0].Parameters, test.Parameters) {
+				t.Fatalf("got: %v expected: %v", targets[0].Parameters, test.Parameters)
+			}
+		})
+	}
+}
@@ -32,7 +32,11 @@ var targets = map[string]Target{
 	   	{{printf "%q" .Name | lower }}: Target{
 	   	  Name: {{printf "%q" .Name}},
 	   	  Description: {{printf "%q" .Description}},
-	   	  Func: {{ printf "%s" .Name}},
+	   	  Deps: []string{ {{range .Dependencies}}{{printf "%q" . | lower}}, {{end}} },
+	   	  Params: []gopherParam{ {{range .Parameters}}{Name: {{printf "%q" .Name}}, Type: {{printf "%q" .Type}}}, {{end}} },
+	   	  Func: func(ctx context.Context, gopher *Gopher, args []any) error {
+	   	  	return {{ printf "%s" .Name}}(ctx, gopher{{range $i, $param := .Parameters}}, args[{{$i}}].({{$param.Type}}){{end}})
+	   	  },
 	   	},
 
 	   {{end}}
@@ -43,8 +47,10 @@ const BinaryName = "target"
 const TargetsFile = "targets.go"
 
 type Target struct {
-	Name        string
-	Description string
+	Name         string
+	Description  string
+	Dependencies []string    // Names of targets declared using [DepsDirective].
+	Parameters   []Parameter // Arguments taken after context.Context and *runtime.Gopher.
 }
 
 // Compile a gopher binary using the provided dependencies. Note dir is assumed to exist when called.
@@ -180,7 +186,7 @@ func initGoModule(ctx context.Context, gopher runtime.Gopher, dir string) (retEr
 func NormalizeComment(comment string) string {
 	switch {
 	case strings.HasPrefix(comment, "//"):
-		return strings.TrimPrefix(comment, "// ")
+		return strings.TrimPrefix(strings.TrimPrefix(comment, "//"), " ")
 	case strings.HasPrefix(comment, "/*"):
 		return strings.TrimSpace(
 			strings.TrimSuffix(
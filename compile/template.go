@@ -19,14 +19,53 @@ import (
 	"os"
 	"os/signal"
 	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+	"unicode"
 )
 
+var errGopherMissingArgument = errors.New("missing argument")
+var errGopherUnexpectedArgument = errors.New("unexpected argument")
+var errGopherUnknownFlag = errors.New("unknown flag")
+var errGopherDuplicateTarget = errors.New("already invoked with different arguments")
+
+// Flags of the session itself. They may be given before or between targets.
+var gopherSessionFlags = []string{"-l", "-k", "--keep-going"}
+
 type Target struct {
 	Name        string
 	Description string
-	Func        func(context.Context, *Gopher) error
+	Deps        []string
+	Params      []gopherParam
+	Func        func(context.Context, *Gopher, []any) error
+}
+
+// A typed argument of a target. Bools are optional flags, everything else is required.
+type gopherParam struct {
+	Name string
+	Type string
+}
+
+// Error returned when a target's command-line arguments can not be parsed.
+type gopherArgError struct {
+	Target string
+	Param  string // Empty if the argument did not match a parameter.
+	Type   string
+	Value  string
+	Err    error
+}
+
+func (err *gopherArgError) Error() string {
+	if err.Param == "" {
+		return fmt.Sprintf("target %s: %s: %q", err.Target, err.Err, err.Value)
+	}
+	return fmt.Sprintf("target %s: argument %s (%s): %s", err.Target, err.Param, err.Type, err.Err)
+}
+
+func (err *gopherArgError) Unwrap() error {
+	return err.Err
 }
 
 func main() {
@@ -37,29 +76,289 @@ func main() {
 	)
 	defer stop()
 	if err := Main(ctx, os.Stdout, os.Args[1:]); err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
+		// Failures of Gopher.Run were printed as soon as they happened
+		if !AlreadyPrinted(err) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
 		os.Exit(1)
 	}
 }
 
 func Main(ctx context.Context, stdout io.Writer, args []string) error {
-	if len(args) < 1 {
-		return errors.New(`missing argument: "<target>"`)
+	invocations, flags, err := parseInvocations(args)
+	if err != nil {
+		return err
 	}
-	if args[0] == "-l" {
+	if slices.Contains(flags, "-l") {
 		PrintTargets()
 		return nil
+	} else if len(invocations) < 1 {
+		return errors.New(`missing argument: "<target>"`)
+	}
+
+	session := session{
+		Stdout:    stdout,
+		KeepGoing: slices.Contains(flags, "-k") || slices.Contains(flags, "--keep-going"),
+		results:   map[string]error{},
+	}
+	return session.Run(ctx, invocations)
+}
+
+// A target to run and its parsed arguments.
+type gopherInvocation struct {
+	Key  string
+	Args []any
+}
+
+/*
+Splits args into targets followed by their arguments and returns any session flags found between them.
+Each target consumes as many arguments as it takes, the next argument must then be a target or a session flag.
+A target may be given more than once only with the same arguments since it runs at most once.
+*/
+func parseInvocations(args []string) ([]gopherInvocation, []string, error) {
+	var invocations []gopherInvocation
+	var flags []string
+	for len(args) > 0 {
+		if slices.Contains(gopherSessionFlags, args[0]) {
+			flags = append(flags, args[0])
+			args = args[1:]
+			continue
+		}
+
+		target, ok := targets[args[0]]
+		if !ok && len(invocations) > 0 {
+			last := targets[invocations[len(invocations)-1].Key]
+			return nil, nil, &gopherArgError{Target: last.Name, Value: args[0], Err: errGopherUnexpectedArgument}
+		} else if !ok && strings.HasPrefix(args[0], "-") {
+			return nil, nil, fmt.Errorf("unknown flag: %s", args[0])
+		} else if !ok {
+			return nil, nil, fmt.Errorf("unknown target: %s", args[0])
+		}
+		values, rest, err := parseArgs(target, args[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, invocation := range invocations {
+			if invocation.Key == args[0] && !slices.Equal(invocation.Args, values) {
+				raw := strings.Join(args[:len(args)-len(rest)], " ")
+				return nil, nil, &gopherArgError{Target: target.Name, Value: raw, Err: errGopherDuplicateTarget}
+			}
+		}
+		invocations = append(invocations, gopherInvocation{Key: args[0], Args: values})
+		args = rest
+	}
+	return invocations, flags, nil
+}
+
+/*
+Parses the target's arguments from the front of args and returns any that were not consumed.
+Arguments are given positionally in order or as flags: "--name value" or "--name=value".
+Bools may only be given as flags and default to false.
+*/
+func parseArgs(target Target, args []string) ([]any, []string, error) {
+	values := make([]any, len(target.Params))
+	for len(args) > 0 {
+		flag, ok := flagName(args[0])
+		if !ok {
+			// Positional arguments fill the next unset non-bool parameter
+			index := -1
+			for i, param := range target.Params {
+				if param.Type != "bool" && values[i] == nil {
+					index = i
+					break
+				}
+			}
+			if index == -1 {
+				break
+			}
+			value, err := target.Params[index].Parse(target.Name, args[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			values[index] = value
+			args = args[1:]
+			continue
+		}
+
+		flag, raw, hasValue := strings.Cut(flag, "=")
+		index := slices.IndexFunc(target.Params, func(param gopherParam) bool {
+			return param.Name == flag || param.Flag() == flag
+		})
+		if index == -1 && slices.Contains(gopherSessionFlags, args[0]) {
+			// Left for parseInvocations, parameters of the same name take precedence
+			break
+		} else if index == -1 {
+			return nil, nil, &gopherArgError{Target: target.Name, Value: args[0], Err: errGopherUnknownFlag}
+		}
+		param := target.Params[index]
+		args = args[1:]
+		if !hasValue && param.Type == "bool" {
+			raw = "true"
+		} else if !hasValue && len(args) == 0 {
+			return nil, nil, &gopherArgError{Target: target.Name, Param: param.Name, Type: param.Type, Err: errGopherMissingArgument}
+		} else if !hasValue {
+			raw, args = args[0], args[1:]
+		}
+		value, err := param.Parse(target.Name, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		values[index] = value
+	}
+
+	for i, param := range target.Params {
+		if values[i] != nil {
+			continue
+		} else if param.Type == "bool" {
+			values[i] = false
+			continue
+		}
+		return nil, nil, &gopherArgError{Target: target.Name, Param: param.Name, Type: param.Type, Err: errGopherMissingArgument}
 	}
-	if target, ok := targets[args[0]]; ok {
-		return target.Func(ctx, &Gopher{
+	return values, args, nil
+}
+
+// Returns the flag name without leading dashes if arg looks like a flag.
+func flagName(arg string) (string, bool) {
+	name := strings.TrimLeft(arg, "-")
+	if len(name) == len(arg) || len(name) == 0 || !unicode.IsLetter(rune(name[0])) {
+		return "", false
+	}
+	return name, true
+}
+
+// Returns the kebab-case flag name of the parameter. Ex: dryRun -> dry-run
+func (param gopherParam) Flag() string {
+	var builder strings.Builder
+	for i, r := range param.Name {
+		if unicode.IsUpper(r) && i > 0 {
+			builder.WriteRune('-')
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+func (param gopherParam) Parse(target string, raw string) (any, error) {
+	var value any
+	var err error
+	switch param.Type {
+	case "string":
+		value = raw
+	case "bool":
+		value, err = strconv.ParseBool(raw)
+	case "int":
+		value, err = strconv.Atoi(raw)
+	case "int64":
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case "uint":
+		var parsed uint64
+		parsed, err = strconv.ParseUint(raw, 10, 0)
+		value = uint(parsed)
+	case "float64":
+		value, err = strconv.ParseFloat(raw, 64)
+	case "time.Duration":
+		value, err = time.ParseDuration(raw)
+	default:
+		err = fmt.Errorf("unsupported type")
+	}
+	if err != nil {
+		return nil, &gopherArgError{Target: target, Param: param.Name, Type: param.Type, Value: raw, Err: err}
+	}
+	return value, nil
+}
+
+// Returns the usage string for a parameter. Ex: <version string> or [--dry-run]
+func (param gopherParam) Usage() string {
+	if param.Type == "bool" {
+		return fmt.Sprintf("[--%s]", param.Flag())
+	}
+	return fmt.Sprintf("<%s %s>", param.Name, param.Type)
+}
+
+/*
+Runs invocations in order. Results are shared between invocations so each target, whether
+invoked or a dependency, is run at most once. Targets only repeat with identical arguments, see [parseInvocations]. By default the first failure stops the session, with KeepGoing every
+invocation is attempted and a summary of the failures is printed at the end.
+*/
+type session struct {
+	Stdout    io.Writer
+	KeepGoing bool
+	results   map[string]error // Results of targets that have already run keyed by target key.
+}
+
+func (session *session) Run(ctx context.Context, invocations []gopherInvocation) error {
+	var failures []string
+	for _, invocation := range invocations {
+		err := session.run(ctx, invocation)
+		if err != nil && !session.KeepGoing {
+			return err
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", targets[invocation.Key].Name, err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(session.Stdout, "---\nFailed %d of %d targets:\n", len(failures), len(invocations))
+	for _, failure := range failures {
+		fmt.Fprintf(session.Stdout, "  %s\n", failure)
+	}
+	return fmt.Errorf("%d of %d targets failed", len(failures), len(invocations))
+}
+
+// Runs the invocation's dependencies in topological order and then the target itself.
+func (session *session) run(ctx context.Context, invocation gopherInvocation) error {
+	for _, key := range order(invocation.Key) {
+		target := targets[key]
+		// Targets that already ran, including as a dependency of an earlier invocation, are not run again
+		if err, ok := session.results[key]; ok && err != nil && key != invocation.Key {
+			return fmt.Errorf("dependency %s: %w", target.Name, err)
+		} else if ok && err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+
+		var args []any
+		if key == invocation.Key {
+			args = invocation.Args
+		}
+		err := target.Func(ctx, &Gopher{
 			GoConfig: GoConfig{
 				GoBin: "go",
 			},
-			Stdout: os.Stdout,
+			Stdout: session.Stdout,
 			Target: target.Name,
-		})
+		}, args)
+		session.results[key] = err
+		if err != nil && key != invocation.Key {
+			return fmt.Errorf("dependency %s: %w", target.Name, err)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the keys of the target and its transitive dependencies in the order they should run.
+func order(name string) []string {
+	var order []string
+	seen := map[string]bool{}
+	var visit func(string)
+	visit = func(key string) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		for _, dep := range targets[key].Deps {
+			visit(dep)
+		}
+		order = append(order, key)
 	}
-	return fmt.Errorf("unknown target: %s", args[0])
+	visit(name)
+	return order
 }
 
 func PrintTargets() {
@@ -69,6 +368,17 @@ func PrintTargets() {
 	for _, name := range keys {
 		target := targets[name]
 		name = strings.ToLower(name)
-		fmt.Printf("  %8s: %s\n", name, strings.ReplaceAll(target.Description, "\n", "\n"+strings.Repeat(" ", max(len(name), 8)+4)))
+		description := target.Description
+		if len(target.Params) > 0 {
+			var usage []string
+			for _, param := range target.Params {
+				usage = append(usage, param.Usage())
+			}
+			description += "\nArguments: " + strings.Join(usage, " ")
+		}
+		if len(target.Deps) > 0 {
+			description += "\nDepends on: " + strings.Join(target.Deps, ", ")
+		}
+		fmt.Printf("  %8s: %s\n", name, strings.ReplaceAll(description, "\n", "\n"+strings.Repeat(" ", max(len(name), 8)+4)))
 	}
 }
@@ -0,0 +1,125 @@
+package compile
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sessionSource = `//go:build ignore && gopher
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/ohhfishal/gopher/runtime"
+)
+
+// Common names the generated main must not collide with
+type Param struct{}
+type ArgError struct{}
+type Invocation struct{}
+
+var ErrMissingArgument, ErrUnexpectedArgument, ErrUnknownFlag error
+
+func Build(ctx context.Context, gopher *Gopher) error {
+	fmt.Fprintln(gopher.Stdout, "ran build")
+	return nil
+}
+
+//gopher:deps Build
+func Test(ctx context.Context, gopher *Gopher) error {
+	fmt.Fprintln(gopher.Stdout, "ran test")
+	return nil
+}
+
+func Release(ctx context.Context, gopher *Gopher, version string) error {
+	fmt.Fprintln(gopher.Stdout, "ran release", version)
+	return nil
+}
+
+func Fail(ctx context.Context, gopher *Gopher) error {
+	fmt.Fprintln(gopher.Stdout, "ran fail")
+	return fmt.Errorf("failed")
+}
+`
+
+func TestSession(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a gopher binary")
+	}
+	targets, _, err := parseTargets([]byte(sessionSource))
+	if err != nil {
+		t.Fatalf("parsing targets: %s", err)
+	}
+	// Inside the module so the generated main imports this version of the runtime
+	dir, err := os.MkdirTemp(".", "session")
+	if err != nil {
+		t.Fatalf("creating directory: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.WriteFile(filepath.Join(dir, TargetsFile), []byte(sessionSource), 0o644); err != nil {
+		t.Fatalf("writing targets: %s", err)
+	}
+	mainFile, err := os.Create(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("creating main.go: %s", err)
+	}
+	defer mainFile.Close()
+	if err := writeMain(mainFile, targets); err != nil {
+		t.Fatalf("writing main.go: %s", err)
+	}
+	binary := filepath.Join(t.TempDir(), BinaryName)
+	build := exec.Command("go", "build", "-tags", "gopher", "-o", binary, "main.go", TargetsFile)
+	build.Dir = dir
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building: %s: %s", err, output)
+	}
+
+	tests := []struct {
+		Args     []string
+		Expected string
+		ErrorMsg string
+	}{
+		{Args: []string{"build", "test"}, Expected: "ran build\nran test\n"},
+		// Build already ran as a dependency of test
+		{Args: []string{"test", "build"}, Expected: "ran build\nran test\n"},
+		{Args: []string{"build", "build"}, Expected: "ran build\n"},
+		{Args: []string{"release", "v1", "release", "--version=v1"}, Expected: "ran release v1\n"},
+		{
+			Args:     []string{"release", "v1", "release", "v2"},
+			ErrorMsg: `target Release: already invoked with different arguments: "release v2"`,
+		},
+		// Session flags are recognised between and after targets
+		{Args: []string{"fail", "-k", "build"}, Expected: "ran fail\nran build\n", ErrorMsg: "1 of 2 targets failed"},
+		{Args: []string{"fail", "build", "--keep-going"}, Expected: "ran fail\nran build\n", ErrorMsg: "1 of 2 targets failed"},
+		{Args: []string{"fail", "build"}, Expected: "ran fail\n", ErrorMsg: "failed"},
+		{Args: []string{"build", "-x"}, ErrorMsg: `target Build: unknown flag: "-x"`},
+	}
+	for _, test := range tests {
+		output, err := exec.Command(binary, test.Args...).CombinedOutput()
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("%v: got error: %s: %s", test.Args, err, output)
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(string(output), test.ErrorMsg)) {
+			t.Fatalf("%v: got: %v: %s expected error containing: %s", test.Args, err, output, test.ErrorMsg)
+		}
+		if got := strings.Join(ranLines(string(output)), ""); got != test.Expected {
+			t.Fatalf("%v: got: %q expected: %q", test.Args, got, test.Expected)
+		}
+	}
+}
+
+// Returns the lines printed by the targets.
+func ranLines(output string) []string {
+	var lines []string
+	for line := range strings.Lines(output) {
+		if strings.HasPrefix(line, "ran ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
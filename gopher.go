@@ -20,11 +20,12 @@ func Devel(ctx context.Context, gopher *Gopher) error {
 		return err
 	}
 	var status Status
-	return gopher.Run(ctx, NowAnd(OnFileChange(1*time.Second, ".go")),
+	options := RunOptions{CancelOnEvent: true}
+	return gopher.RunWith(ctx, options, NowAnd(OnFileChange(1*time.Second, ".go", ".mod", ".sum")),
 		status.Start(),
 		&GoBuild{},
 		&GoFormat{},
-		&GoTest{},
+		&GoTest{Affected: true},
 		&GoVet{},
 		&GoModTidy{},
 		status.Done(),
@@ -43,16 +44,65 @@ func CICD(ctx context.Context, gopher *Gopher) error {
 		status.Start(),
 		&GoBuild{
 			Output: "target/cicd",
+			Stamp: Stamp{
+				Version: "github.com/ohhfishal/gopher/cache.version",
+			},
 		},
-		&GoFormat{
-			CheckOnly: true,
+		Parallel(
+			&GoFormat{
+				CheckOnly: true,
+			},
+			&GoTest{
+				Race:         true,
+				Timeout:      2 * time.Minute,
+				JUnitReport:  "target/junit.xml",
+				CoverProfile: "target/cover.out",
+			},
+			&GoVet{},
+		),
+		&GoCoverage{
+			Profile:   "target/cover.out",
+			ReportDir: "target/coverage",
+		},
+		&GoBench{
+			MaxRegression: 10,
 		},
-		&GoTest{},
-		&GoVet{},
 		status.Done(),
 	)
 }
 
+// Fuzzes every fuzz target now then once a day. Failing inputs are left in testdata/fuzz.
+func Nightly(ctx context.Context, gopher *Gopher) error {
+	return gopher.Run(ctx, NowAnd(AfterEvery(24*time.Hour)),
+		&GoFuzz{
+			FuzzTime: 10 * time.Minute,
+		},
+	)
+}
+
+// Cross compiles gopher then packages the binaries into target/release.
+func Release(ctx context.Context, gopher *Gopher) error {
+	return gopher.Run(ctx, Now(),
+		&GoBuild{
+			Packages: []string{"."},
+			Flags:    []string{"-trimpath"},
+			Platforms: []Platform{
+				{OS: "linux", Arch: "amd64"},
+				{OS: "linux", Arch: "arm64"},
+				{OS: "darwin", Arch: "amd64"},
+				{OS: "darwin", Arch: "arm64"},
+				{OS: "windows", Arch: "amd64"},
+			},
+			Stamp: Stamp{
+				Version: "github.com/ohhfishal/gopher/cache.version",
+			},
+		},
+		&ReleaseArchives{
+			Files: []string{"README.md"},
+		},
+	)
+}
+
 // Removes all local build artifacts.
 func Clean(ctx context.Context, gopher *Gopher) error {
 	return os.RemoveAll("target")
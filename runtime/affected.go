@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+// A package as reported by `go list -json`. Only the fields used are decoded.
+type goPackage struct {
+	ImportPath   string
+	Dir          string
+	Standard     bool
+	DepOnly      bool // Only listed since a package matching the patterns depends on it.
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Module       *struct {
+		Main bool
+	}
+}
+
+// Reports whether the package is part of the main module rather than a dependency.
+func (pkg goPackage) isMain() bool {
+	return !pkg.Standard && pkg.Module != nil && pkg.Module.Main
+}
+
+// Lists the packages matching the patterns and their dependencies using `go list -deps -json`.
+func listPackages(ctx context.Context, goBin string, patterns []string) ([]goPackage, error) {
+	args := append([]string{"list", "-deps", "-json"}, patterns...)
+	cmd := CommandContext(ctx, goBin, args...)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("%w: %s", err, exitErr.Stderr)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var packages []goPackage
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decoding go list output: %w", err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+/*
+Returns the import paths of the packages affected by the changes: packages containing a changed
+file and every package that transitively imports them. Packages whose tests import an affected
+package are included but do not affect their own importers. Packages only listed as
+dependencies of the patterns are never returned.
+All is true when every package should be tested instead, such as when go.mod or go.sum changed.
+*/
+func affectedPackages(changes ChangeSet, packages []goPackage) (affected []string, all bool) {
+	if changes.Incomplete {
+		return nil, true
+	}
+
+	byDir := map[string]string{}
+	depOnly := map[string]bool{}
+	importers := map[string][]string{}
+	testImporters := map[string][]string{}
+	for _, pkg := range packages {
+		if !pkg.isMain() {
+			continue
+		}
+		byDir[pkg.Dir] = pkg.ImportPath
+		depOnly[pkg.ImportPath] = pkg.DepOnly
+		for _, imported := range pkg.Imports {
+			importers[imported] = append(importers[imported], pkg.ImportPath)
+		}
+		for _, imported := range slices.Concat(pkg.TestImports, pkg.XTestImports) {
+			testImporters[imported] = append(testImporters[imported], pkg.ImportPath)
+		}
+	}
+
+	seen := map[string]bool{}
+	var queue []string
+	for _, path := range changes.Paths() {
+		switch filepath.Base(path) {
+		case "go.mod", "go.sum", "go.work", "go.work.sum":
+			return nil, true
+		}
+		if filepath.Ext(path) != ".go" {
+			continue
+		}
+		pkg, ok := byDir[filepath.Dir(path)]
+		if !ok {
+			// Most likely a new package, which go list did not know about
+			return nil, true
+		}
+		if !seen[pkg] {
+			seen[pkg] = true
+			queue = append(queue, pkg)
+		}
+	}
+
+	tested := map[string]bool{}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		tested[pkg] = true
+		for _, importer := range testImporters[pkg] {
+			tested[importer] = true
+		}
+		for _, importer := range importers[pkg] {
+			if !seen[importer] {
+				seen[importer] = true
+				queue = append(queue, importer)
+			}
+		}
+	}
+	for pkg := range tested {
+		if !depOnly[pkg] {
+			affected = append(affected, pkg)
+		}
+	}
+	slices.Sort(affected)
+	return affected, false
+}
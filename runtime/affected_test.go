@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	main := &struct{ Main bool }{Main: true}
+	packages := []goPackage{
+		{ImportPath: "fmt", Dir: "/go/src/fmt", Standard: true},
+		{ImportPath: "example.com/dep", Dir: "/mod/dep", Module: &struct{ Main bool }{}},
+		{ImportPath: "m/util", Dir: "/m/util", Module: main},
+		{ImportPath: "m/core", Dir: "/m/core", Module: main, Imports: []string{"fmt", "m/util"}},
+		{ImportPath: "m/api", Dir: "/m/api", Module: main, Imports: []string{"m/core"}},
+		{ImportPath: "m/cmd", Dir: "/m/cmd", Module: main, Imports: []string{"m/api"}},
+		{ImportPath: "m/other", Dir: "/m/other", Module: main, TestImports: []string{"m/api"}},
+		{ImportPath: "m/leaf", Dir: "/m/leaf", Module: main, XTestImports: []string{"m/other"}},
+		{ImportPath: "m/hidden", Dir: "/m/hidden", Module: main, DepOnly: true, Imports: []string{"m/util"}},
+	}
+
+	tests := []struct {
+		Name     string
+		Changes  ChangeSet
+		Affected []string
+		All      bool
+	}{
+		{
+			Name:     "leaf",
+			Changes:  changeSet("/m/cmd/main.go"),
+			Affected: []string{"m/cmd"},
+		},
+		{
+			Name:     "transitive",
+			Changes:  changeSet("/m/core/core.go"),
+			Affected: []string{"m/api", "m/cmd", "m/core", "m/other"},
+		},
+		{
+			Name:     "tests do not propagate",
+			Changes:  changeSet("/m/other/other.go"),
+			Affected: []string{"m/leaf", "m/other"},
+		},
+		{
+			Name:     "dependency only packages are skipped",
+			Changes:  changeSet("/m/util/util.go"),
+			Affected: []string{"m/api", "m/cmd", "m/core", "m/other", "m/util"},
+		},
+		{
+			Name:    "non go files",
+			Changes: changeSet("/m/README.md"),
+		},
+		{
+			Name:    "go.mod",
+			Changes: changeSet("/m/cmd/main.go", "/m/go.mod"),
+			All:     true,
+		},
+		{
+			Name:    "new package",
+			Changes: changeSet("/m/new/new.go"),
+			All:     true,
+		},
+		{
+			Name:    "incomplete",
+			Changes: ChangeSet{Incomplete: true},
+			All:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			affected, all := affectedPackages(test.Changes, packages)
+			if all != test.All {
+				t.Fatalf("got all: %t expected: %t", all, test.All)
+			} else if !slices.Equal(affected, test.Affected) {
+				t.Fatalf("got: %v expected: %v", affected, test.Affected)
+			}
+		})
+	}
+}
+
+func changeSet(paths ...string) ChangeSet {
+	var changes ChangeSet
+	for _, path := range paths {
+		changes = changes.Merge(ChangeSet{Changes: []FileChange{{Path: path}}})
+	}
+	return changes
+}
@@ -0,0 +1,362 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+var _ Runner = &GoBench{}
+var _ Validator = &GoBench{}
+
+// File [GoBench] stores its baselines in by default.
+const DefaultBenchBaseline = ".gopher/bench.json"
+
+// Number of baselines kept, dropping the oldest.
+const maxBenchBaselines = 20
+
+/*
+[GoBench] implements the [Runner] interface and exec's `go test -bench` with -benchmem.
+
+Results are compared with a baseline using benchstat style statistics then stored
+keyed by the git commit. When the working tree has uncommitted changes, runs are compared with
+the baseline of the commit checked out, otherwise with the most recent baseline of another commit.
+*/
+type GoBench struct {
+	Packages  []string // Positional args. If empty, defaults to ["./..."].
+	Flags     []string // Any additional flags to be passed to go test.
+	Pattern   string   // Only run benchmarks matching the regexp. If empty, defaults to ".". Effectively go test -bench.
+	Count     int      // Times to run each benchmark. If 0, defaults to 6. Effectively go test -count.
+	BenchTime string   // Ex: 1s or 100x. Effectively go test -benchtime.
+	// Percentage a benchmark may get significantly worse by before failing. If 0, regressions are only reported.
+	MaxRegression float64
+	Alpha         float64 // Significance level of the comparison. If 0, defaults to 0.05.
+	Baseline      string  // File baselines are stored in. If empty, defaults to [DefaultBenchBaseline].
+}
+
+// Samples of each benchmark keyed by "package.BenchmarkName" then unit. Ex: ns/op
+type BenchResults map[string]map[string]BenchSample
+
+type benchBaseline struct {
+	Commit  string       `json:"commit"`
+	Dirty   bool         `json:"dirty"`
+	Time    time.Time    `json:"time"`
+	Results BenchResults `json:"results"`
+}
+
+func (baseline benchBaseline) key() string {
+	if baseline.Dirty {
+		return baseline.Commit + "-dirty"
+	}
+	return baseline.Commit
+}
+
+// Comparison of one metric of a benchmark with its baseline.
+type benchDelta struct {
+	Name     string
+	Unit     string
+	Old      BenchSample
+	New      BenchSample
+	Delta    float64 // Percent change of the mean.
+	P        float64
+	Worse    bool // Whether the change is a regression, such as more ns/op or fewer MB/s.
+	Baseline bool // Whether there was a baseline to compare to.
+}
+
+// Parses the output of `go test -bench`. Lines that are not results are ignored.
+func parseBenchOutput(reader io.Reader) (BenchResults, error) {
+	results := BenchResults{}
+	pkg := ""
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(name)
+			continue
+		}
+		// Format: BenchmarkName-8  1000  1052 ns/op  128 B/op  2 allocs/op
+		fields := strings.Fields(line)
+		if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := fields[0]
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+		if results[name] == nil {
+			results[name] = map[string]BenchSample{}
+		}
+		for i := 2; i < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", fields[0], err)
+			}
+			sample := results[name][fields[i+1]]
+			sample.Values = append(sample.Values, value)
+			results[name][fields[i+1]] = sample
+		}
+	}
+	return results, scanner.Err()
+}
+
+// Compares every metric in results with the baseline sorted by name then unit.
+func compareBench(old BenchResults, results BenchResults) []benchDelta {
+	var deltas []benchDelta
+	for _, name := range slices.Sorted(maps.Keys(results)) {
+		for _, unit := range slices.Sorted(maps.Keys(results[name])) {
+			delta := benchDelta{
+				Name: name,
+				Unit: unit,
+				New:  results[name][unit],
+				P:    1,
+			}
+			if sample, ok := old[name][unit]; ok {
+				delta.Baseline = true
+				delta.Old = sample
+				delta.P = mannWhitneyU(sample.Values, delta.New.Values)
+				if mean := sample.Mean(); mean != 0 {
+					delta.Delta = 100 * (delta.New.Mean() - mean) / mean
+				}
+				// Throughput such as MB/s is better when higher, everything else when lower
+				if strings.HasSuffix(unit, "/s") {
+					delta.Worse = delta.Delta < 0
+				} else {
+					delta.Worse = delta.Delta > 0
+				}
+			}
+			deltas = append(deltas, delta)
+		}
+	}
+	return deltas
+}
+
+func (bench *GoBench) alpha() float64 {
+	if bench.Alpha == 0 {
+		return 0.05
+	}
+	return bench.Alpha
+}
+
+func (bench *GoBench) baselinePath() string {
+	if bench.Baseline == "" {
+		return DefaultBenchBaseline
+	}
+	return bench.Baseline
+}
+
+// Returns an error if the count or maximum regression is negative, alpha is not between 0 and 1
+// or the baseline path is a directory.
+func (bench *GoBench) Validate() error {
+	var errs []error
+	if bench.Count < 0 {
+		errs = append(errs, fmt.Errorf("count must not be negative: %d", bench.Count))
+	}
+	if bench.MaxRegression < 0 {
+		errs = append(errs, fmt.Errorf("max regression must not be negative: %f", bench.MaxRegression))
+	}
+	if bench.Alpha < 0 || bench.Alpha >= 1 {
+		errs = append(errs, fmt.Errorf("alpha must be between 0 and 1: %f", bench.Alpha))
+	}
+	// Checked up front since baselines are only written after the benchmarks ran
+	if info, err := os.Stat(bench.baselinePath()); err == nil && info.IsDir() {
+		errs = append(errs, fmt.Errorf("baseline must be a file: %s is a directory", bench.baselinePath()))
+	}
+	return errors.Join(errs...)
+}
+
+func (bench *GoBench) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Go Bench")
+	printer.Start()
+	err := bench.run(ctx, printer, *args)
+	printer.Done(err)
+	return err
+}
+
+func (bench *GoBench) run(ctx context.Context, printer *pretty.Printer, gopher Gopher) error {
+	if err := bench.Validate(); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	packages := bench.Packages
+	if len(packages) == 0 {
+		packages = append(packages, "./...")
+	}
+	pattern := cmp.Or(bench.Pattern, ".")
+	count := bench.Count
+	if count == 0 {
+		count = 6
+	}
+	cmdArgs := []string{"test", "-run=^$", "-bench=" + pattern, "-benchmem", "-count=" + strconv.Itoa(count)}
+	if bench.BenchTime != "" {
+		cmdArgs = append(cmdArgs, "-benchtime="+bench.BenchTime)
+	}
+	cmdArgs = append(cmdArgs, bench.Flags...)
+	cmdArgs = append(cmdArgs, packages...)
+
+	var output bytes.Buffer
+	gopher.Stdout = &output
+	runner := &ExecCmdRunner{
+		Name: gopher.GoConfig.GoBin,
+		Args: cmdArgs,
+	}
+	if err := runner.Run(ctx, &gopher); err != nil {
+		printer.Write(output.Bytes())
+		return err
+	}
+	results, err := parseBenchOutput(&output)
+	if err != nil {
+		return err
+	}
+
+	current := benchBaseline{Time: time.Now(), Results: results}
+	current.Commit, current.Dirty, err = gitCommit(ctx, "")
+	if err != nil {
+		printer.Warn(fmt.Errorf("baselines will not be keyed by commit: %w", err))
+	}
+	baselines, err := bench.readBaselines()
+	if err != nil {
+		printer.Warn(fmt.Errorf("ignoring baselines: %w", err))
+	}
+	var old BenchResults
+	if previous, ok := previousBaseline(baselines, current); ok {
+		old = previous.Results
+		fmt.Fprintf(printer, "  comparing with %s from %s\n", shortCommit(previous.key()), previous.Time.Format(time.DateTime))
+	}
+
+	deltas := compareBench(old, results)
+	writeBenchTable(pretty.NewIndentedWriter(printer, "  "), deltas, bench.alpha())
+	var errs []error
+	for _, delta := range deltas {
+		if bench.MaxRegression > 0 && delta.Worse && delta.P < bench.alpha() && math.Abs(delta.Delta) > bench.MaxRegression {
+			errs = append(errs, fmt.Errorf("%s: %s regressed by %.2f%%", delta.Name, delta.Unit, math.Abs(delta.Delta)))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if err := bench.writeBaselines(baselines, current); err != nil {
+		printer.Warn(fmt.Errorf("writing baseline: %w", err))
+	}
+	return nil
+}
+
+func shortCommit(key string) string {
+	if len(key) > 12 && !strings.HasSuffix(key, "-dirty") {
+		return key[:12]
+	} else if len(key) > 18 {
+		return key[:12] + "-dirty"
+	} else if key == "" {
+		return "unknown commit"
+	}
+	return key
+}
+
+/*
+Returns the baseline to compare the current run with: the committed run of the same commit
+if the working tree is dirty, otherwise the most recent run of a different commit.
+Outside of a git repository the most recent run is used.
+*/
+func previousBaseline(baselines []benchBaseline, current benchBaseline) (benchBaseline, bool) {
+	if current.Dirty {
+		for _, baseline := range baselines {
+			if baseline.Commit == current.Commit && !baseline.Dirty {
+				return baseline, true
+			}
+		}
+	}
+	var latest benchBaseline
+	found := false
+	for _, baseline := range baselines {
+		if (current.Commit == "" || baseline.key() != current.key()) && (!found || baseline.Time.After(latest.Time)) {
+			latest, found = baseline, true
+		}
+	}
+	return latest, found
+}
+
+func (bench *GoBench) readBaselines() ([]benchBaseline, error) {
+	content, err := os.ReadFile(bench.baselinePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var baselines []benchBaseline
+	if err := json.Unmarshal(content, &baselines); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", bench.baselinePath(), err)
+	}
+	return baselines, nil
+}
+
+// Replaces the baseline of the current commit and keeps only the most recent baselines.
+func (bench *GoBench) writeBaselines(baselines []benchBaseline, current benchBaseline) error {
+	baselines = slices.DeleteFunc(baselines, func(baseline benchBaseline) bool {
+		return baseline.key() == current.key()
+	})
+	baselines = append(baselines, current)
+	slices.SortFunc(baselines, func(a, b benchBaseline) int {
+		return a.Time.Compare(b.Time)
+	})
+	if len(baselines) > maxBenchBaselines {
+		baselines = baselines[len(baselines)-maxBenchBaselines:]
+	}
+
+	content, err := json.MarshalIndent(baselines, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(bench.baselinePath()), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(bench.baselinePath(), append(content, '\n'), 0644)
+}
+
+// Writes a benchstat style table of the deltas, coloured by whether changes are significant.
+func writeBenchTable(stdout io.Writer, deltas []benchDelta, alpha float64) {
+	rows := [][]tableCell{{{Text: "NAME"}, {Text: "UNIT"}, {Text: "OLD"}, {Text: "NEW"}, {Text: "DELTA"}}}
+	for _, delta := range deltas {
+		old, change := "-", tableCell{Text: "-"}
+		if delta.Baseline {
+			old = formatSample(delta.Old)
+			n := fmt.Sprintf("n=%d+%d", len(delta.Old.Values), len(delta.New.Values))
+			if delta.P < alpha {
+				change = tableCell{Text: fmt.Sprintf("%+.2f%% (p=%.3f %s)", delta.Delta, delta.P, n), Color: pretty.OK}
+				if delta.Worse {
+					change.Color = pretty.ERROR
+				}
+			} else {
+				change.Text = fmt.Sprintf("~ (p=%.3f %s)", delta.P, n)
+			}
+		}
+		rows = append(rows, []tableCell{
+			{Text: delta.Name},
+			{Text: delta.Unit},
+			{Text: old},
+			{Text: formatSample(delta.New)},
+			change,
+		})
+	}
+	writeTable(stdout, rows, 2)
+}
+
+// Formats the mean and spread. Ex: 1052 ± 2%
+func formatSample(sample BenchSample) string {
+	return fmt.Sprintf("%s ± %.0f%%", strconv.FormatFloat(sample.Mean(), 'g', 4, 64), sample.Spread())
+}
@@ -0,0 +1,160 @@
+package runtime
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const benchOutput = `goos: linux
+goarch: amd64
+pkg: example.com/a
+cpu: Example CPU
+BenchmarkSum-8   	 1000000	      1000 ns/op	     128 B/op	       2 allocs/op
+BenchmarkSum-8   	 1000000	      1100 ns/op	     128 B/op	       2 allocs/op
+BenchmarkCopy-8  	     500	      2000 ns/op	  500.00 MB/s
+PASS
+ok  	example.com/a	3.000s
+pkg: example.com/b
+BenchmarkSum-8   	 1000000	       900 ns/op	       0 B/op	       0 allocs/op
+`
+
+func TestParseBenchOutput(t *testing.T) {
+	results, err := parseBenchOutput(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if len(results) != 3 {
+		t.Fatalf("got: %v expected 3 benchmarks", results)
+	}
+	sum := results["example.com/a.BenchmarkSum-8"]
+	if values := sum["ns/op"].Values; len(values) != 2 || values[0] != 1000 || values[1] != 1100 {
+		t.Fatalf("got: %v expected: [1000 1100]", values)
+	}
+	if mean := sum["ns/op"].Mean(); mean != 1050 {
+		t.Fatalf("got: %f expected: 1050", mean)
+	}
+	if variance := sum["ns/op"].Variance(); variance != 5000 {
+		t.Fatalf("got: %f expected: 5000", variance)
+	}
+	if values := results["example.com/a.BenchmarkCopy-8"]["MB/s"].Values; len(values) != 1 || values[0] != 500 {
+		t.Fatalf("got: %v expected: [500]", values)
+	}
+	if _, ok := results["example.com/b.BenchmarkSum-8"]["allocs/op"]; !ok {
+		t.Fatalf("expected allocs/op for example.com/b")
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		Old      []float64
+		New      []float64
+		Expected float64
+	}{
+		{Old: []float64{1, 2, 3}, New: []float64{4, 5, 6}, Expected: 0.1},
+		{Old: []float64{1, 2, 3, 4, 5, 6}, New: []float64{7, 8, 9, 10, 11, 12}, Expected: 2.0 / 924},
+		{Old: []float64{7, 8, 9, 10, 11, 12}, New: []float64{1, 2, 3, 4, 5, 6}, Expected: 2.0 / 924},
+		{Old: []float64{1, 3, 5}, New: []float64{2, 4, 6}, Expected: 0.7},
+		{Old: []float64{5, 5, 5}, New: []float64{5, 5, 5}, Expected: 1},
+		{Old: nil, New: []float64{1}, Expected: 1},
+	}
+	for _, test := range tests {
+		if p := mannWhitneyU(test.Old, test.New); math.Abs(p-test.Expected) > 1e-9 {
+			t.Fatalf("%v %v: got: %f expected: %f", test.Old, test.New, p, test.Expected)
+		}
+	}
+}
+
+func TestCompareBench(t *testing.T) {
+	sample := func(values ...float64) BenchSample {
+		return BenchSample{Values: values}
+	}
+	old := BenchResults{
+		"BenchmarkA": {"ns/op": sample(100, 101, 99, 100, 102, 98), "MB/s": sample(10, 10, 10, 10, 10, 10)},
+	}
+	results := BenchResults{
+		"BenchmarkA": {"ns/op": sample(120, 121, 119, 120, 122, 118), "MB/s": sample(8, 8, 8, 8, 8, 8)},
+		"BenchmarkB": {"ns/op": sample(1)},
+	}
+	deltas := compareBench(old, results)
+	if len(deltas) != 3 {
+		t.Fatalf("got: %v expected 3 deltas", deltas)
+	}
+	throughput, nsPerOp, added := deltas[0], deltas[1], deltas[2]
+	if throughput.Unit != "MB/s" || !throughput.Worse || throughput.Delta != -20 {
+		t.Fatalf("expected MB/s to get worse by 20%%: %+v", throughput)
+	}
+	if nsPerOp.Unit != "ns/op" || !nsPerOp.Worse || nsPerOp.Delta != 20 || nsPerOp.P >= 0.05 {
+		t.Fatalf("expected ns/op to get significantly worse by 20%%: %+v", nsPerOp)
+	}
+	if added.Name != "BenchmarkB" || added.Baseline {
+		t.Fatalf("expected no baseline for BenchmarkB: %+v", added)
+	}
+}
+
+func TestPreviousBaseline(t *testing.T) {
+	now := time.Now()
+	baselines := []benchBaseline{
+		{Commit: "a", Time: now.Add(-3 * time.Hour)},
+		{Commit: "b", Time: now.Add(-2 * time.Hour)},
+		{Commit: "b", Dirty: true, Time: now.Add(-1 * time.Hour)},
+	}
+	tests := []struct {
+		Current  benchBaseline
+		Expected string
+	}{
+		{Current: benchBaseline{Commit: "b", Dirty: true}, Expected: "b"},
+		{Current: benchBaseline{Commit: "b"}, Expected: "b-dirty"},
+		{Current: benchBaseline{Commit: "c"}, Expected: "b-dirty"},
+		{Current: benchBaseline{Commit: "a", Dirty: true}, Expected: "a"},
+		{Current: benchBaseline{}, Expected: "b-dirty"},
+	}
+	for _, test := range tests {
+		previous, ok := previousBaseline(baselines, test.Current)
+		if !ok || previous.key() != test.Expected {
+			t.Fatalf("%s: got: %s expected: %s", test.Current.key(), previous.key(), test.Expected)
+		}
+	}
+	if _, ok := previousBaseline(nil, benchBaseline{Commit: "a"}); ok {
+		t.Fatalf("expected no baseline")
+	}
+}
+
+func TestGoBench(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs benchmarks")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":      "module example.com/bench\n\ngo 1.22\n",
+		"sum_test.go": "package bench\n\nimport \"testing\"\n\nfunc BenchmarkSum(b *testing.B) {\n\tfor range b.N {\n\t\t_ = make([]byte, 8)\n\t}\n}\n",
+		"bench.go":    "package bench\n",
+	})
+
+	bench := GoBench{Count: 2, BenchTime: "10x", Baseline: filepath.Join(dir, "bench.json")}
+	for i := range 2 {
+		var stdout strings.Builder
+		if err := bench.Run(context.Background(), &Gopher{GoConfig: GoConfig{GoBin: "go"}, Stdout: &stdout}); err != nil {
+			t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+		}
+		if !strings.Contains(stdout.String(), "example.com/bench.BenchmarkSum") {
+			t.Fatalf("expected benchmark in output: %s", stdout.String())
+		}
+		if i == 1 && !strings.Contains(stdout.String(), "n=2+2") {
+			t.Fatalf("expected comparison with the first run: %s", stdout.String())
+		}
+	}
+	baselines, err := bench.readBaselines()
+	if err != nil || len(baselines) != 1 {
+		t.Fatalf("got: %v %v expected one baseline", baselines, err)
+	}
+
+	if err := (&GoBench{Count: -1}).Validate(); err == nil {
+		t.Fatalf("expected invalid count")
+	}
+	if err := (&GoBench{Baseline: dir}).Validate(); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Fatalf("got: %v expected the baseline directory to be rejected", err)
+	}
+}
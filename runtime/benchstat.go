@@ -0,0 +1,151 @@
+package runtime
+
+import (
+	"math"
+	"slices"
+)
+
+// Summary of the samples of one metric of a benchmark.
+type BenchSample struct {
+	Values []float64 `json:"values"`
+}
+
+func (sample BenchSample) Mean() float64 {
+	if len(sample.Values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range sample.Values {
+		sum += value
+	}
+	return sum / float64(len(sample.Values))
+}
+
+// Returns the sample variance, 0 if there are fewer than two values.
+func (sample BenchSample) Variance() float64 {
+	if len(sample.Values) < 2 {
+		return 0
+	}
+	mean := sample.Mean()
+	var sum float64
+	for _, value := range sample.Values {
+		sum += (value - mean) * (value - mean)
+	}
+	return sum / float64(len(sample.Values)-1)
+}
+
+// Returns the standard deviation as a percentage of the mean, like the ± column of benchstat.
+func (sample BenchSample) Spread() float64 {
+	mean := sample.Mean()
+	if mean == 0 {
+		return 0
+	}
+	return 100 * math.Sqrt(sample.Variance()) / mean
+}
+
+/*
+Returns the two-sided p-value of the Mann-Whitney U test, which is the test benchstat uses
+to tell whether two samples differ. The exact distribution is used for small samples
+without ties, otherwise the normal approximation with a tie correction is used.
+*/
+func mannWhitneyU(old []float64, new []float64) float64 {
+	n1, n2 := len(old), len(new)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type ranked struct {
+		value float64
+		old   bool
+	}
+	all := make([]ranked, 0, n1+n2)
+	for _, value := range old {
+		all = append(all, ranked{value, true})
+	}
+	for _, value := range new {
+		all = append(all, ranked{value, false})
+	}
+	slices.SortFunc(all, func(a, b ranked) int {
+		switch {
+		case a.value < b.value:
+			return -1
+		case a.value > b.value:
+			return 1
+		}
+		return 0
+	})
+
+	// Tied values share the average of their ranks
+	var rankSum, tieCorrection float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].old {
+				rankSum += rank
+			}
+		}
+		if count := float64(j - i); count > 1 {
+			ties = true
+			tieCorrection += count*count*count - count
+		}
+		i = j
+	}
+	u := rankSum - float64(n1*(n1+1))/2
+
+	if !ties && n1*n2 <= 2500 {
+		return exactMannWhitneyP(n1, n2, u)
+	}
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance == 0 {
+		return 1
+	}
+	// Continuity correction
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	return min(1, math.Erfc(max(z, 0)/math.Sqrt2))
+}
+
+// Returns the exact two-sided p-value of observing u, counting the orderings that give each U.
+func exactMannWhitneyP(n1 int, n2 int, u float64) float64 {
+	// counts[i][j][k] would be the orderings of i and j values with U = k, rolled into two dimensions
+	maxU := n1 * n2
+	counts := make([][]float64, n2+1)
+	for j := range counts {
+		counts[j] = make([]float64, maxU+1)
+		counts[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		next := make([][]float64, n2+1)
+		next[0] = make([]float64, maxU+1)
+		next[0][0] = 1
+		for j := 1; j <= n2; j++ {
+			next[j] = make([]float64, maxU+1)
+			for k := 0; k <= i*j; k++ {
+				// The largest value either belongs to the first sample, adding j to U, or to the second
+				if k >= j {
+					next[j][k] += counts[j][k-j]
+				}
+				next[j][k] += next[j-1][k]
+			}
+		}
+		counts = next
+	}
+
+	var total, lower, upper float64
+	for k, count := range counts[n2] {
+		total += count
+		if float64(k) <= u {
+			lower += count
+		}
+		if float64(k) >= u {
+			upper += count
+		}
+	}
+	return min(1, 2*min(lower, upper)/total)
+}
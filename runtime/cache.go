@@ -1,25 +1,57 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/ohhfishal/nibbles/assert"
-	"golang.org/x/time/rate"
+	"github.com/ohhfishal/gopher/pretty"
 )
 
+// Time to wait after the first change for the rest of a burst of changes.
+const settleDelay = 120 * time.Millisecond
+
+// Directories ignored by default: version control, gopher's cache and common build outputs.
+var DefaultExclude = []string{".git/", ".gopher/", "target/", "node_modules/"}
+
+/*
+Options for watching files using [OnFileChangeWith].
+
+Globs are matched against slash separated paths relative to the root being watched
+using [path.Match] syntax. Globs without a slash match the base name at any depth,
+otherwise they match from the root and "**" matches any number of directories.
+Like .gitignore files, a trailing slash only matches directories and a leading "!"
+re-includes a previously excluded path.
+*/
+type WatchOptions struct {
+	Roots      []string      // Directories to watch recursively. If empty, defaults to [os.Getwd].
+	Interval   time.Duration // Minimum duration between updates.
+	Extensions []string      // If not empty, only files with one of these extensions trigger an update.
+	Include    []string      // If not empty, only files matching one of these globs trigger an update.
+	Exclude    []string      // Files and directories to ignore. If nil, defaults to [DefaultExclude].
+	GitIgnore  bool          // When true, also ignores paths matched by .gitignore files under the roots.
+	Warnings   io.Writer     // Where watcher errors are printed as warnings. If nil, defaults to [os.Stdout].
+}
+
 /*
 Cache that stops blocks additional runners from running until a file has been chaged.
 */
 type fileCache struct {
-	Extensions []string      // List of extensions to watch for changes.
-	Path       string        // Directory to watch for file changes. If empty, defaults to [os.Getwd].
-	Interval   time.Duration // Minimum duration between updates.
+	WatchOptions
+	watcher    *fsnotify.Watcher
+	roots      []string                // Absolute paths of Roots.
+	excludes   []ignoreRule            // Parsed Exclude globs.
+	gitignores map[string][]ignoreRule // Rules of each .gitignore keyed by its directory.
+	watched    map[string]bool         // Directories currently being watched.
 }
 
 func (cache *fileCache) newWatcher() (*fsnotify.Watcher, error) {
@@ -27,60 +59,274 @@ func (cache *fileCache) newWatcher() (*fsnotify.Watcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	if cache.Path == "" {
+	cache.watcher = watcher
+	cache.watched = map[string]bool{}
+	cache.gitignores = map[string][]ignoreRule{}
+
+	roots := cache.Roots
+	if len(roots) == 0 {
 		path, err := os.Getwd()
 		if err != nil {
+			watcher.Close()
 			return nil, err
 		}
-		cache.Path = path
+		roots = []string{path}
+	}
+	exclude := cache.Exclude
+	if exclude == nil {
+		exclude = DefaultExclude
 	}
+	cache.excludes = parseGitIgnore([]byte(strings.Join(exclude, "\n")), ".")
 
-	if err := watcher.Add(cache.Path); err != nil {
-		return nil, fmt.Errorf("adding path to watch list: %w", err)
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("resolving root: %w", err)
+		}
+		cache.roots = append(cache.roots, root)
+		if _, err := cache.addTree(root); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("walking directories: %w", err)
+		}
 	}
-	if err := filepath.WalkDir(cache.Path, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() {
-			if err := watcher.Add(path); err != nil {
-				return fmt.Errorf("adding path to watch list: %w", err)
+	return watcher, nil
+}
+
+/*
+Watches the directory and every directory under it that is not ignored.
+Returns the relevant files found, which is used to report files inside of newly created directories.
+*/
+func (cache *fileCache) addTree(dir string) ([]FileChange, error) {
+	var found []FileChange
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil && path != dir && errors.Is(err, fs.ErrNotExist) {
+			// Removed while walking
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			if cache.relevant(path) {
+				found = append(found, FileChange{Path: path, Op: fsnotify.Create})
 			}
+			return nil
 		}
+		if cache.ignored(path, true) {
+			return filepath.SkipDir
+		}
+		if cache.GitIgnore {
+			cache.loadGitIgnore(path)
+		}
+		if err := cache.watcher.Add(path); err != nil {
+			return fmt.Errorf("adding path to watch list: %w", err)
+		}
+		cache.watched[path] = true
 		return nil
-	}); err != nil {
-		return nil, fmt.Errorf("walking directories: %w", err)
+	})
+	return found, err
+}
+
+// Stops watching the directory and every directory under it.
+func (cache *fileCache) removeTree(dir string) {
+	for path := range cache.watched {
+		if !isWithin(dir, path) {
+			continue
+		}
+		// The watch is already gone if the directory was deleted
+		_ = cache.watcher.Remove(path)
+		delete(cache.watched, path)
+		delete(cache.gitignores, path)
 	}
-	return watcher, nil
 }
 
-// Event returns an event that yields when a file is changed.
+func isWithin(dir string, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// Reads the .gitignore in dir if any, replacing the rules previously read from it.
+func (cache *fileCache) loadGitIgnore(dir string) {
+	_, rel, ok := cache.relative(dir)
+	if !ok {
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		delete(cache.gitignores, dir)
+		return
+	}
+	cache.gitignores[dir] = parseGitIgnore(content, rel)
+}
+
+// Returns the root containing the path and the slash separated path relative to it.
+func (cache *fileCache) relative(path string) (string, string, bool) {
+	for _, root := range cache.roots {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return root, filepath.ToSlash(rel), true
+	}
+	return "", "", false
+}
+
+func (cache *fileCache) ignored(path string, isDir bool) bool {
+	root, rel, ok := cache.relative(path)
+	if !ok {
+		return true
+	} else if rel == "." {
+		return false
+	}
+
+	rules := slices.Clone(cache.excludes)
+	// Sorting puts parent directories first so deeper .gitignore files take precedence
+	for _, dir := range slices.Sorted(maps.Keys(cache.gitignores)) {
+		if isWithin(root, dir) {
+			rules = append(rules, cache.gitignores[dir]...)
+		}
+	}
+	return ignoredBy(rules, rel, isDir)
+}
+
+// Reports whether a change to the file should trigger an update.
+func (cache *fileCache) relevant(path string) bool {
+	if len(cache.Extensions) > 0 && !slices.Contains(cache.Extensions, filepath.Ext(path)) {
+		return false
+	} else if cache.ignored(path, false) {
+		return false
+	} else if len(cache.Include) == 0 {
+		return true
+	}
+	_, rel, _ := cache.relative(path)
+	return slices.ContainsFunc(cache.Include, func(glob string) bool {
+		return matchGlob(glob, rel)
+	})
+}
+
+// Event returns an event that yields a [ChangeSet] when a file is changed.
 func (cache *fileCache) Event() (Event, error) {
 	watcher, err := cache.newWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("adding files to watch: %w", err)
 	}
-	limiter := rate.NewLimiter(rate.Every(cache.Interval), 1)
+	var lastYield time.Time
 	return func(yield func(_ any) bool) {
 		for {
-			select {
-			case event, ok := <-watcher.Events:
-				// filter out events we don't care about
-				if !ok || !slices.Contains(cache.Extensions, filepath.Ext(event.Name)) {
-					continue
-				}
-				if !limiter.Allow() {
-					continue
-				}
-				// NOTE: This delay is to allow editors to fully write their changes
-				time.Sleep(120 * time.Millisecond)
-				if !yield(nil) {
-					return
-				}
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					continue
-				}
-				// TODO: I don't think this triggers so letting it panic
-				assert.Unreachable(err.Error())
+			changes, ok := cache.collect(watcher.Events, watcher.Errors, lastYield)
+			if !ok {
+				return
+			}
+			lastYield = time.Now()
+			if !yield(changes) {
+				return
 			}
 		}
 	}, nil
 }
+
+/*
+Blocks until a relevant file changes then keeps collecting changes until the burst settles
+and at least Interval has passed since the last yield.
+*/
+func (cache *fileCache) collect(events <-chan fsnotify.Event, errs <-chan error, lastYield time.Time) (ChangeSet, bool) {
+	var changes ChangeSet
+	var deadline <-chan time.Time
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return changes, false
+			}
+			found := cache.handle(event)
+			if len(found) == 0 {
+				continue
+			}
+			changes = changes.Merge(ChangeSet{
+				Changes: found,
+				Time:    time.Now(),
+			})
+			if deadline == nil {
+				deadline = cache.deadline(lastYield)
+			}
+		case <-deadline:
+			return changes, true
+		case err, ok := <-errs:
+			if !ok {
+				// Closed channels are always ready so stop selecting on it
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				cache.warn("file watcher error", err)
+				continue
+			}
+			// Events were dropped so resync the watch list and report the changes as incomplete
+			cache.warn("file watcher overflowed, rescanning", err)
+			cache.rescan()
+			changes = changes.Merge(ChangeSet{Incomplete: true, Time: time.Now()})
+			if deadline == nil {
+				deadline = cache.deadline(lastYield)
+			}
+		}
+	}
+}
+
+// Returns a channel that fires once the changes have settled and Interval has passed since lastYield.
+func (cache *fileCache) deadline(lastYield time.Time) <-chan time.Time {
+	// NOTE: This delay is to allow editors to fully write their changes
+	return time.After(max(settleDelay, time.Until(lastYield.Add(cache.Interval))))
+}
+
+// Stops watching every directory then walks the roots again.
+func (cache *fileCache) rescan() {
+	for _, root := range cache.roots {
+		cache.removeTree(root)
+	}
+	for _, root := range cache.roots {
+		if _, err := cache.addTree(root); err != nil {
+			cache.warn("rescanning "+root, err)
+		}
+	}
+}
+
+func (cache *fileCache) warn(msg string, err error) {
+	slog.Warn(msg, "err", err)
+	stdout := cache.Warnings
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	pretty.Fwarnf(stdout, "%s: %s\n", msg, err)
+}
+
+/*
+Keeps the watch list in sync with directories being created and removed and
+returns the changes the event represents that should trigger an update.
+*/
+func (cache *fileCache) handle(event fsnotify.Event) []FileChange {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		if cache.watched[event.Name] {
+			cache.removeTree(event.Name)
+			return nil
+		}
+	}
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if cache.ignored(event.Name, true) {
+				return nil
+			}
+			found, err := cache.addTree(event.Name)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				cache.warn("watching new directory "+event.Name, err)
+			}
+			return found
+		}
+	}
+	if filepath.Base(event.Name) == ".gitignore" && cache.GitIgnore {
+		cache.loadGitIgnore(filepath.Dir(event.Name))
+	}
+	if !cache.relevant(event.Name) {
+		return nil
+	}
+	return []FileChange{{Path: event.Name, Op: event.Op}}
+}
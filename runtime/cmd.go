@@ -1,23 +1,67 @@
 package runtime
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 /*
-Runner that uses [exec.CommandContext] to create and run commands.
+Runner that uses [CommandContext] to create and run commands.
 This needs to be done to ensure the command can be canceled and invoked
-several times.
+several times. Canceling terminates the command's whole process group.
+Output is written line by line as the command runs.
 You may use [ExecCommand] to initialize the struct with a similar API to [exec.Command].
 */
 type ExecCmdRunner struct {
-	// TODO: Provide more options akin to exec.Shell
-	Name       string   // Same as [exec.CommandContext]
-	Args       []string // Same as [exec.CommandContext]
-	Dir        string   // Same as [exec.CommandContext]
-	HideOutput bool     // When true, does not print command output to [Gopher].Stdout
+	Name     string   // Same as [exec.CommandContext]
+	Args     []string // Same as [exec.CommandContext]
+	Dir      string   // Same as [exec.CommandContext]
+	Env      []string // Added to the environment of the current process. Ex: GOOS=linux
+	ClearEnv bool     // When true, the command only gets Env instead of inheriting the current environment.
+	Stdin    io.Reader
+	Stdout   io.Writer     // Where stdout is written. If nil, defaults to [Gopher].Stdout.
+	Stderr   io.Writer     // Where stderr is written. If nil, defaults to the same writer as stdout.
+	Timeout  time.Duration // If > 0, the command is killed after running for the duration.
+	// Time the command has to exit after SIGTERM before it is sent SIGKILL. If 0, defaults to [DefaultGracePeriod].
+	GracePeriod time.Duration
+	HideOutput  bool // When true, does not print command output to [Gopher].Stdout
+}
+
+// Cause of the context of a runner whose own Timeout elapsed, as opposed to a deadline of its parent.
+var errRunnerTimeout = errors.New("runner timed out")
+
+/*
+Error returned by [ExecCmdRunner] when a command fails to run, exits non-zero or times out.
+*/
+type ExitError struct {
+	Command  string // Command line that was run. Ex: go test ./...
+	Code     int    // Exit code or -1 if the command did not exit on its own.
+	TimedOut bool
+	Err      error
+}
+
+func (err *ExitError) Error() string {
+	switch {
+	case err.TimedOut:
+		return fmt.Sprintf("%s: timed out: %s", err.Command, err.Err)
+	case err.Code >= 0:
+		return fmt.Sprintf("%s: exit status %d", err.Command, err.Code)
+	}
+	return fmt.Sprintf("%s: %s", err.Command, err.Err)
+}
+
+func (err *ExitError) Unwrap() error {
+	return err.Err
 }
 
 /*
@@ -30,15 +74,110 @@ func ExecCommand(name string, args ...string) Runner {
 	}
 }
 
+// Returns the command line, quoting arguments that would be split by a shell.
+func (runner *ExecCmdRunner) String() string {
+	parts := []string{runner.Name}
+	for _, arg := range runner.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
 func (runner *ExecCmdRunner) Run(ctx context.Context, args *Gopher) error {
+	if runner.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, runner.Timeout, errRunnerTimeout)
+		defer cancel()
+	}
 	cmd := exec.CommandContext(ctx, runner.Name, runner.Args...)
+	release := setProcessGroup(cmd, cmp.Or(runner.GracePeriod, DefaultGracePeriod))
 	cmd.Dir = runner.Dir
-	output, err := cmd.CombinedOutput()
-	if !runner.HideOutput {
-		fmt.Fprint(args.Stdout, string(output))
+	if runner.ClearEnv {
+		cmd.Env = append([]string{}, runner.Env...)
+	} else if len(runner.Env) > 0 {
+		// Later values take precedence
+		cmd.Env = append(os.Environ(), runner.Env...)
 	}
-	if err != nil {
-		return err
+	cmd.Stdin = runner.Stdin
+
+	stdout := runner.Stdout
+	if stdout == nil {
+		stdout = args.Stdout
+	}
+	if runner.HideOutput {
+		stdout = io.Discard
+	}
+	outLines, errLines := lineWriters(stdout, runner.Stderr)
+	cmd.Stdout, cmd.Stderr = outLines, errLines
+
+	err := cmd.Run()
+	release()
+	outLines.Flush()
+	errLines.Flush()
+	if err == nil {
+		return nil
+	}
+
+	exitErr := &ExitError{Command: runner.String(), Code: -1, Err: err}
+	var processErr *exec.ExitError
+	if errors.Is(context.Cause(ctx), errRunnerTimeout) {
+		exitErr.TimedOut = true
+		exitErr.Err = fmt.Errorf("after %s: %w", runner.Timeout, context.DeadlineExceeded)
+	} else if errors.As(err, &processErr) {
+		exitErr.Code = processErr.ExitCode()
+	}
+	return exitErr
+}
+
+/*
+Returns writers for the stdout and stderr of a command. Lines of stdout and stderr are not
+interleaved. If stderr is nil, both write to stdout. If stdout is nil, its output is discarded.
+*/
+func lineWriters(stdout io.Writer, stderr io.Writer) (*lineWriter, *lineWriter) {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	var lock sync.Mutex
+	outLines := &lineWriter{stdout: stdout, lock: &lock}
+	if stderr == nil {
+		return outLines, outLines
+	}
+	return outLines, &lineWriter{stdout: stderr, lock: &lock}
+}
+
+// Writer that only forwards complete lines so output of concurrent writers is not mixed mid line.
+type lineWriter struct {
+	stdout  io.Writer
+	lock    *sync.Mutex
+	partial []byte
+}
+
+func (writer *lineWriter) Write(content []byte) (int, error) {
+	writer.partial = append(writer.partial, content...)
+	index := bytes.LastIndexByte(writer.partial, '\n')
+	if index < 0 {
+		return len(content), nil
+	}
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if _, err := writer.stdout.Write(writer.partial[:index+1]); err != nil {
+		return 0, err
+	}
+	writer.partial = append(writer.partial[:0], writer.partial[index+1:]...)
+	return len(content), nil
+}
+
+// Writes any remaining partial line.
+func (writer *lineWriter) Flush() error {
+	if len(writer.partial) == 0 {
+		return nil
 	}
-	return nil
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	_, err := writer.stdout.Write(writer.partial)
+	writer.partial = writer.partial[:0]
+	return err
 }
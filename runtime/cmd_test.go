@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecCmdRunner(t *testing.T) {
+	t.Setenv("GOPHER_INHERITED", "inherited")
+	tests := []struct {
+		Runner   ExecCmdRunner
+		Output   string
+		ErrorMsg string
+	}{
+		{
+			Runner: ExecCmdRunner{Name: "sh", Args: []string{"-c", "echo $GOPHER_INHERITED $GOPHER_ADDED"}, Env: []string{"GOPHER_ADDED=added"}},
+			Output: "inherited added\n",
+		},
+		{
+			Runner: ExecCmdRunner{Name: "/bin/sh", Args: []string{"-c", "echo \"[$GOPHER_INHERITED]\""}, ClearEnv: true},
+			Output: "[]\n",
+		},
+		{
+			Runner: ExecCmdRunner{Name: "cat", Stdin: strings.NewReader("from stdin")},
+			Output: "from stdin",
+		},
+		{
+			Runner: ExecCmdRunner{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}, Stderr: &strings.Builder{}},
+			Output: "out\n",
+		},
+		{
+			Runner: ExecCmdRunner{Name: "sh", Args: []string{"-c", "echo out; echo err >&2"}, HideOutput: true},
+			Output: "",
+		},
+		{
+			Runner:   ExecCmdRunner{Name: "sh", Args: []string{"-c", "echo failing; exit 3"}},
+			Output:   "failing\n",
+			ErrorMsg: `sh -c "echo failing; exit 3": exit status 3`,
+		},
+		{
+			Runner:   ExecCmdRunner{Name: "sleep", Args: []string{"10"}, Timeout: 50 * time.Millisecond},
+			ErrorMsg: "sleep 10: timed out: after 50ms: context deadline exceeded",
+		},
+		{
+			Runner:   ExecCmdRunner{Name: "gopher-command-that-does-not-exist"},
+			ErrorMsg: "gopher-command-that-does-not-exist: exec:",
+		},
+	}
+	for _, test := range tests {
+		var stdout strings.Builder
+		err := test.Runner.Run(context.Background(), &Gopher{Stdout: &stdout})
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("%s: got error: %s", test.Runner.String(), err.Error())
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+			t.Fatalf("%s: got: %v expected error containing: %s", test.Runner.String(), err, test.ErrorMsg)
+		}
+		if stdout.String() != test.Output {
+			t.Fatalf("%s: got: %q expected: %q", test.Runner.String(), stdout.String(), test.Output)
+		}
+	}
+}
+
+func TestExecCmdRunnerExitError(t *testing.T) {
+	runner := ExecCmdRunner{Name: "sh", Args: []string{"-c", "exit 7"}}
+	err := runner.Run(context.Background(), &Gopher{Stdout: &strings.Builder{}})
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("got: %T expected: *ExitError", err)
+	}
+	if exitErr.Code != 7 || exitErr.TimedOut || exitErr.Command != `sh -c "exit 7"` {
+		t.Fatalf("unexpected error: %+v", exitErr)
+	}
+}
+
+// Writer that reports each write on a channel.
+type notifyWriter struct {
+	lock   sync.Mutex
+	writes chan string
+}
+
+func (writer *notifyWriter) Write(content []byte) (int, error) {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	writer.writes <- string(content)
+	return len(content), nil
+}
+
+func TestExecCmdRunnerStreams(t *testing.T) {
+	writer := &notifyWriter{writes: make(chan string, 10)}
+	runner := ExecCmdRunner{
+		Name: "sh",
+		Args: []string{"-c", "printf 'first\\nsec'; sleep 0.2; printf 'ond\\n'; exec sleep 5"},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- runner.Run(ctx, &Gopher{Stdout: writer})
+	}()
+
+	select {
+	case line := <-writer.writes:
+		if line != "first\n" {
+			t.Fatalf("got: %q expected: %q", line, "first\n")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected output before the command exited")
+	}
+	select {
+	case line := <-writer.writes:
+		if line != "second\n" {
+			t.Fatalf("got: %q expected partial lines to be joined", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected the second line before the command exited")
+	}
+	cancel()
+	<-done
+}
+
+func TestTimeoutOfParent(t *testing.T) {
+	runners := []Runner{
+		&ExecCmdRunner{Name: "sleep", Args: []string{"10"}, Timeout: time.Hour, GracePeriod: 100 * time.Millisecond},
+		&Shell{Script: "sleep 10", Timeout: time.Hour, GracePeriod: 100 * time.Millisecond},
+	}
+	for _, runner := range runners {
+		// Only the runner's own Timeout counts as timing out
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := runner.Run(ctx, &Gopher{Stdout: &strings.Builder{}})
+		cancel()
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("%v: got: %v expected: *ExitError", runner, err)
+		} else if exitErr.TimedOut {
+			t.Fatalf("%v: got: %v expected the parent's deadline", runner, err)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+var _ Runner = &GoCoverage{}
+
+// File [GoCoverage] stores its baseline in by default.
+const DefaultCoverageBaseline = ".gopher/coverage.json"
+
+/*
+[GoCoverage] implements the [Runner] interface and checks the statement coverage
+of cover profiles, such as ones written using [GoTest].CoverProfile, and of coverage
+directories written by binaries built using [GoBuild].Cover.
+Everything given is merged before checking, so unit and integration tests may be combined.
+Minimums are percentages between 0 and 100.
+*/
+type GoCoverage struct {
+	Profile   string   // Cover profile to read.
+	Profiles  []string // Additional cover profiles to merge with Profile.
+	CoverDirs []string // GOCOVERDIR directories to merge, converted using go tool covdata.
+	// If set, writes the merged profile to cover.out, a go tool cover -html report to cover.html
+	// and a function level summary to cover.txt in the directory. Ex: target/coverage
+	ReportDir string
+	Minimum   float64            // Minimum coverage of the total and every package.
+	Packages  map[string]float64 // Minimum coverage of packages by import path, replacing Minimum for them.
+	// When true, fails if the total or a package's coverage decreased compared to the baseline.
+	// The baseline is updated every time the checks pass.
+	NoDecrease bool
+	Baseline   string // File the baseline is stored in. If empty, defaults to [DefaultCoverageBaseline].
+}
+
+// Statement coverage of a package or the total.
+type CoverageResult struct {
+	Name       string // Import path of the package or "total".
+	Statements int
+	Covered    int
+}
+
+// Returns the percentage of statements covered, 100 if there are no statements.
+func (result CoverageResult) Percent() float64 {
+	if result.Statements == 0 {
+		return 100
+	}
+	return 100 * float64(result.Covered) / float64(result.Statements)
+}
+
+// Coverage stored between runs to detect decreases.
+type coverageBaseline struct {
+	Total    float64            `json:"total"`
+	Packages map[string]float64 `json:"packages"`
+}
+
+// Position of a block in a cover profile. Ex: example.com/pkg/file.go:10.2,12.16
+type coverBlock struct {
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+}
+
+func (block coverBlock) compare(other coverBlock) int {
+	return cmp.Or(
+		strings.Compare(block.File, other.File),
+		cmp.Compare(block.StartLine, other.StartLine),
+		cmp.Compare(block.StartCol, other.StartCol),
+		cmp.Compare(block.EndLine, other.EndLine),
+		cmp.Compare(block.EndCol, other.EndCol),
+	)
+}
+
+// A parsed cover profile. Blocks listed more than once, such as when using -coverpkg, are merged.
+type coverProfile struct {
+	Mode       string
+	Statements map[coverBlock]int
+	Counts     map[coverBlock]int
+}
+
+func newCoverProfile() *coverProfile {
+	return &coverProfile{
+		Statements: map[coverBlock]int{},
+		Counts:     map[coverBlock]int{},
+	}
+}
+
+// Parses a cover profile as written by go test -coverprofile.
+func parseCoverProfile(reader io.Reader) (*coverProfile, error) {
+	profile := newCoverProfile()
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if mode, ok := strings.CutPrefix(line, "mode: "); ok {
+			if profile.Mode != "" && profile.Mode != mode {
+				return nil, fmt.Errorf("line %d: mixed cover modes: %s and %s", lineNumber, profile.Mode, mode)
+			}
+			profile.Mode = mode
+			continue
+		}
+
+		// Format: file:startLine.startCol,endLine.endCol statements count
+		var block coverBlock
+		var statements, count int
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("line %d: invalid block: %q", lineNumber, line)
+		}
+		block.File = line[:colon]
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d",
+			&block.StartLine, &block.StartCol, &block.EndLine, &block.EndCol, &statements, &count,
+		); err != nil {
+			return nil, fmt.Errorf("line %d: invalid block: %q: %w", lineNumber, line, err)
+		}
+		profile.add(block, statements, count)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if profile.Mode == "" {
+		return nil, errors.New("missing mode line")
+	}
+	return profile, nil
+}
+
+func (profile *coverProfile) add(block coverBlock, statements int, count int) {
+	profile.Statements[block] = statements
+	if profile.Mode == "set" {
+		profile.Counts[block] = max(profile.Counts[block], count)
+	} else {
+		profile.Counts[block] += count
+	}
+}
+
+/*
+Merges the other profile into this one.
+Profiles using different modes are merged in "set" mode since only whether a block ran is comparable.
+*/
+func (profile *coverProfile) merge(other *coverProfile) {
+	if profile.Mode == "" {
+		profile.Mode = other.Mode
+	} else if profile.Mode != other.Mode && profile.Mode != "set" {
+		profile.Mode = "set"
+		for block, count := range profile.Counts {
+			profile.Counts[block] = min(count, 1)
+		}
+	}
+	for block, statements := range other.Statements {
+		count := other.Counts[block]
+		if profile.Mode == "set" {
+			count = min(count, 1)
+		}
+		profile.add(block, statements, count)
+	}
+}
+
+// Writes the profile in the format written by go test -coverprofile.
+func (profile *coverProfile) WriteTo(stdout io.Writer) (int64, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "mode: %s\n", profile.Mode)
+	for _, block := range slices.SortedFunc(maps.Keys(profile.Statements), coverBlock.compare) {
+		fmt.Fprintf(&builder, "%s:%d.%d,%d.%d %d %d\n",
+			block.File,
+			block.StartLine,
+			block.StartCol,
+			block.EndLine,
+			block.EndCol,
+			profile.Statements[block],
+			profile.Counts[block],
+		)
+	}
+	written, err := io.WriteString(stdout, builder.String())
+	return int64(written), err
+}
+
+// Returns the coverage of each package sorted by import path followed by the total.
+func (profile *coverProfile) results() []CoverageResult {
+	packages := map[string]*CoverageResult{}
+	total := CoverageResult{Name: "total"}
+	for block, statements := range profile.Statements {
+		name := path.Dir(block.File)
+		result, ok := packages[name]
+		if !ok {
+			result = &CoverageResult{Name: name}
+			packages[name] = result
+		}
+		result.Statements += statements
+		total.Statements += statements
+		if profile.Counts[block] > 0 {
+			result.Covered += statements
+			total.Covered += statements
+		}
+	}
+
+	var results []CoverageResult
+	for _, name := range slices.Sorted(maps.Keys(packages)) {
+		results = append(results, *packages[name])
+	}
+	return append(results, total)
+}
+
+func (coverage *GoCoverage) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Go Coverage")
+	printer.Start()
+	err := coverage.run(ctx, printer, *args)
+	printer.Done(err)
+	return err
+}
+
+func (coverage *GoCoverage) run(ctx context.Context, printer *pretty.Printer, gopher Gopher) error {
+	profile, err := coverage.merged(ctx, printer, gopher)
+	if err != nil {
+		return err
+	}
+	if coverage.ReportDir != "" {
+		if err := coverage.writeReports(ctx, printer, gopher, profile); err != nil {
+			return fmt.Errorf("writing reports: %w", err)
+		}
+	}
+	results := profile.results()
+
+	var baseline *coverageBaseline
+	if coverage.NoDecrease {
+		baseline, err = coverage.readBaseline()
+		if err != nil {
+			printer.Warn(fmt.Errorf("ignoring baseline: %w", err))
+		}
+	}
+
+	errs := coverage.check(results, baseline)
+	writeCoverageTable(pretty.NewIndentedWriter(printer, "  "), results, coverage.minimum, baseline)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if coverage.NoDecrease {
+		if err := coverage.writeBaseline(results); err != nil {
+			printer.Warn(fmt.Errorf("writing baseline: %w", err))
+		}
+	}
+	return nil
+}
+
+// Reads and merges the profiles and coverage directories.
+func (coverage *GoCoverage) merged(ctx context.Context, printer *pretty.Printer, gopher Gopher) (*coverProfile, error) {
+	profiles := coverage.Profiles
+	if coverage.Profile != "" {
+		profiles = append([]string{coverage.Profile}, profiles...)
+	}
+	if len(profiles) == 0 && len(coverage.CoverDirs) == 0 {
+		return nil, errors.New("a profile or cover directory is required")
+	}
+
+	if len(coverage.CoverDirs) > 0 {
+		// go tool covdata only writes to files
+		file, err := os.CreateTemp("", "gopher-covdata-*.out")
+		if err != nil {
+			return nil, err
+		}
+		file.Close()
+		defer os.Remove(file.Name())
+
+		cmdArgs := []string{"tool", "covdata", "textfmt", "-i=" + strings.Join(coverage.CoverDirs, ","), "-o=" + file.Name()}
+		if err := runGoTool(ctx, printer, gopher, cmdArgs); err != nil {
+			return nil, fmt.Errorf("converting cover directories: %w", err)
+		}
+		profiles = append(profiles, file.Name())
+	}
+
+	merged := newCoverProfile()
+	for _, name := range profiles {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading cover profile: %w", err)
+		}
+		profile, err := parseCoverProfile(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		merged.merge(profile)
+	}
+	return merged, nil
+}
+
+// Writes the merged profile, an HTML report and a function level summary into ReportDir.
+func (coverage *GoCoverage) writeReports(ctx context.Context, printer *pretty.Printer, gopher Gopher, profile *coverProfile) error {
+	if err := os.MkdirAll(coverage.ReportDir, 0755); err != nil {
+		return err
+	}
+	profilePath := filepath.Join(coverage.ReportDir, "cover.out")
+	file, err := os.Create(profilePath)
+	if err != nil {
+		return err
+	}
+	if _, err := profile.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	htmlPath := filepath.Join(coverage.ReportDir, "cover.html")
+	if err := runGoTool(ctx, printer, gopher, []string{"tool", "cover", "-html=" + profilePath, "-o=" + htmlPath}); err != nil {
+		return fmt.Errorf("html report: %w", err)
+	}
+
+	var summary bytes.Buffer
+	gopher.Stdout = &summary
+	runner := &ExecCmdRunner{
+		Name: gopher.GoConfig.GoBin,
+		Args: []string{"tool", "cover", "-func=" + profilePath},
+	}
+	if err := runner.Run(ctx, &gopher); err != nil {
+		return fmt.Errorf("function summary: %w: %s", err, summary.String())
+	}
+	return os.WriteFile(filepath.Join(coverage.ReportDir, "cover.txt"), summary.Bytes(), 0644)
+}
+
+// Returns the minimum coverage of the package or total.
+func (coverage *GoCoverage) minimum(name string) float64 {
+	if minimum, ok := coverage.Packages[name]; ok {
+		return minimum
+	}
+	return coverage.Minimum
+}
+
+// Returns an error for every result below its minimum or, if baseline is not nil, below the baseline.
+func (coverage *GoCoverage) check(results []CoverageResult, baseline *coverageBaseline) []error {
+	var errs []error
+	for _, result := range results {
+		percent := result.Percent()
+		if minimum := coverage.minimum(result.Name); below(percent, minimum) {
+			errs = append(errs, fmt.Errorf("%s: coverage %.1f%% is below the minimum of %.1f%%", result.Name, percent, minimum))
+		}
+		if previous, ok := baseline.get(result.Name); ok && below(percent, previous) {
+			errs = append(errs, fmt.Errorf("%s: coverage decreased from %.1f%% to %.1f%%", result.Name, previous, percent))
+		}
+	}
+	return errs
+}
+
+// Compares percentages to one decimal place, which is what gets printed.
+func below(percent float64, minimum float64) bool {
+	return math.Round(percent*10) < math.Round(minimum*10)
+}
+
+func (baseline *coverageBaseline) get(name string) (float64, bool) {
+	if baseline == nil {
+		return 0, false
+	} else if name == "total" {
+		return baseline.Total, true
+	}
+	percent, ok := baseline.Packages[name]
+	return percent, ok
+}
+
+func (coverage *GoCoverage) baselinePath() string {
+	if coverage.Baseline == "" {
+		return DefaultCoverageBaseline
+	}
+	return coverage.Baseline
+}
+
+// Returns nil if the baseline has not been written yet.
+func (coverage *GoCoverage) readBaseline() (*coverageBaseline, error) {
+	content, err := os.ReadFile(coverage.baselinePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var baseline coverageBaseline
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", coverage.baselinePath(), err)
+	}
+	return &baseline, nil
+}
+
+func (coverage *GoCoverage) writeBaseline(results []CoverageResult) error {
+	baseline := coverageBaseline{Packages: map[string]float64{}}
+	for _, result := range results {
+		if result.Name == "total" {
+			baseline.Total = result.Percent()
+		} else {
+			baseline.Packages[result.Name] = result.Percent()
+		}
+	}
+	content, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(coverage.baselinePath()), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(coverage.baselinePath(), append(content, '\n'), 0644)
+}
+
+// Writes a table of the coverage of each package, coloured by whether it passed its checks.
+func writeCoverageTable(stdout io.Writer, results []CoverageResult, minimum func(string) float64, baseline *coverageBaseline) {
+	rows := [][]tableCell{{{Text: "PACKAGE"}, {Text: "COVERAGE"}, {Text: "MINIMUM"}, {Text: "CHANGE"}}}
+	for _, result := range results {
+		percent := result.Percent()
+		coverage := tableCell{Text: fmt.Sprintf("%.1f%%", percent), Color: pretty.OK}
+		if below(percent, minimum(result.Name)) {
+			coverage.Color = pretty.ERROR
+		}
+
+		change := tableCell{Text: "-", Color: pretty.OK}
+		if previous, ok := baseline.get(result.Name); ok {
+			change.Text = fmt.Sprintf("%+.1f%%", percent-previous)
+			if below(percent, previous) {
+				change.Color = pretty.ERROR
+			}
+		}
+		rows = append(rows, []tableCell{
+			{Text: result.Name},
+			coverage,
+			{Text: fmt.Sprintf("%.1f%%", minimum(result.Name))},
+			change,
+		})
+	}
+	writeTable(stdout, rows, 1)
+}
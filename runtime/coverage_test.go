@@ -0,0 +1,176 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const coverProfileContent = `mode: set
+example.com/a/a.go:3.10,5.2 2 1
+example.com/a/a.go:7.10,9.2 2 0
+example.com/b/b.go:3.10,5.2 4 1
+example.com/a/a.go:7.10,9.2 2 1
+`
+
+func TestParseCoverProfile(t *testing.T) {
+	profile, err := parseCoverProfile(strings.NewReader(coverProfileContent))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	expected := []CoverageResult{
+		{Name: "example.com/a", Statements: 4, Covered: 4},
+		{Name: "example.com/b", Statements: 4, Covered: 4},
+		{Name: "total", Statements: 8, Covered: 8},
+	}
+	results := profile.results()
+	if len(results) != len(expected) {
+		t.Fatalf("got: %v expected: %v", results, expected)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("got: %v expected: %v", results[i], expected[i])
+		}
+	}
+
+	counted, err := parseCoverProfile(strings.NewReader("mode: count\nexample.com/a/a.go:7.10,9.2 2 5\nexample.com/c/c.go:1.1,2.2 1 0\n"))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	profile.merge(counted)
+	var merged strings.Builder
+	if _, err := profile.WriteTo(&merged); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	expectedProfile := `mode: set
+example.com/a/a.go:3.10,5.2 2 1
+example.com/a/a.go:7.10,9.2 2 1
+example.com/b/b.go:3.10,5.2 4 1
+example.com/c/c.go:1.1,2.2 1 0
+`
+	if merged.String() != expectedProfile {
+		t.Fatalf("got: %q expected: %q", merged.String(), expectedProfile)
+	}
+
+	for _, content := range []string{
+		"example.com/a/a.go:3.10,5.2 2 1\n",
+		"mode: set\nexample.com/a/a.go:3.10,5.2 two 1\n",
+		"mode: set\nmode: count\n",
+	} {
+		if _, err := parseCoverProfile(strings.NewReader(content)); err == nil {
+			t.Fatalf("expected error parsing: %q", content)
+		}
+	}
+}
+
+func TestGoCoverage(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "cover.out")
+	write := func(content string) {
+		if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+	}
+	run := func(coverage GoCoverage) (string, error) {
+		var stdout strings.Builder
+		coverage.Profile = profile
+		coverage.Baseline = filepath.Join(dir, "baseline.json")
+		err := coverage.Run(context.Background(), &Gopher{Stdout: &stdout})
+		return stdout.String(), err
+	}
+
+	// a: 50%, b: 100%, total: 75%
+	write("mode: set\nexample.com/a/a.go:3.10,5.2 2 1\nexample.com/a/a.go:7.10,9.2 2 0\nexample.com/b/b.go:3.10,5.2 4 1\n")
+	tests := []struct {
+		Coverage GoCoverage
+		ErrorMsg string
+	}{
+		{Coverage: GoCoverage{Minimum: 50}},
+		{Coverage: GoCoverage{Minimum: 60}, ErrorMsg: "example.com/a: coverage 50.0% is below the minimum of 60.0%"},
+		{Coverage: GoCoverage{Minimum: 60, Packages: map[string]float64{"example.com/a": 40}}},
+		{Coverage: GoCoverage{Minimum: 80, Packages: map[string]float64{"example.com/a": 0}}, ErrorMsg: "total: coverage 75.0% is below"},
+		{Coverage: GoCoverage{NoDecrease: true}},
+	}
+	for _, test := range tests {
+		output, err := run(test.Coverage)
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+			t.Fatalf("got: %v expected error containing: %s", err, test.ErrorMsg)
+		}
+		if !strings.Contains(output, "example.com/a") || !strings.Contains(output, "75.0%") {
+			t.Fatalf("expected table in output: %s", output)
+		}
+	}
+
+	// a: 25%
+	write("mode: set\nexample.com/a/a.go:3.10,5.2 1 1\nexample.com/a/a.go:7.10,9.2 3 0\nexample.com/b/b.go:3.10,5.2 4 1\n")
+	if _, err := run(GoCoverage{NoDecrease: true}); err == nil || !strings.Contains(err.Error(), "example.com/a: coverage decreased from 50.0% to 25.0%") {
+		t.Fatalf("got: %v expected a decrease", err)
+	}
+	// Failing does not update the baseline
+	if _, err := run(GoCoverage{NoDecrease: true}); err == nil {
+		t.Fatalf("expected the baseline to be unchanged")
+	}
+
+	if err := (&GoCoverage{Profile: filepath.Join(dir, "missing")}).Run(context.Background(), &Gopher{Stdout: &strings.Builder{}}); err == nil {
+		t.Fatalf("expected an error for a missing profile")
+	}
+}
+
+func TestGoCoverageMerge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":           "module example.com/merge\n\ngo 1.22\n",
+		"calc/calc.go":     "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n",
+		"calc/add_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"bad\")\n\t}\n}\n",
+		"main.go":          "package main\n\nimport \"example.com/merge/calc\"\n\nfunc main() {\n\tcalc.Sub(2, 1)\n}\n",
+	})
+	ctx := context.Background()
+	var stdout strings.Builder
+	gopher := &Gopher{GoConfig: GoConfig{GoBin: "go"}, Stdout: &stdout}
+
+	// Unit tests only cover Add while the binary only covers Sub
+	if err := (&GoTest{Packages: []string{"./calc"}, CoverProfile: "unit.out"}).Run(ctx, gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+	if err := (&GoBuild{Output: "merge", Cover: true}).Run(ctx, gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+	coverDir := filepath.Join(dir, "covdata")
+	if err := os.Mkdir(coverDir, 0755); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	cmd := exec.Command(filepath.Join(dir, "merge"))
+	cmd.Env = append(os.Environ(), "GOCOVERDIR="+coverDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), output)
+	}
+
+	coverage := GoCoverage{
+		Profile:   "unit.out",
+		CoverDirs: []string{coverDir},
+		ReportDir: filepath.Join("target", "coverage"),
+		Packages:  map[string]float64{"example.com/merge/calc": 100},
+	}
+	if err := coverage.Run(ctx, gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+	for _, name := range []string{"cover.out", "cover.html", "cover.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "target", "coverage", name)); err != nil {
+			t.Fatalf("expected report: %s", err.Error())
+		}
+	}
+	summary, err := os.ReadFile(filepath.Join(dir, "target", "coverage", "cover.txt"))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !strings.Contains(string(summary), "Sub") || !strings.Contains(string(summary), "total:") {
+		t.Fatalf("expected function summary: %s", summary)
+	}
+}
@@ -0,0 +1,246 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+// Key [GoBuild] stores the [Artifact]s of a cross compiled build under. See [Gopher.Artifacts].
+const ArtifactsKey = "go-build"
+
+// Output template of [GoBuild] when building for several platforms and Output is empty.
+const DefaultPlatformOutput = "target/{{.Name}}_{{.OS}}_{{.Arch}}{{.Ext}}"
+
+/*
+A target platform of a cross compiled [GoBuild].
+*/
+type Platform struct {
+	OS    string   // GOOS. Ex: linux
+	Arch  string   // GOARCH. Ex: arm64
+	Arm   string   // GOARM, only used when Arch is arm. Ex: 7
+	Amd64 string   // GOAMD64, only used when Arch is amd64. Ex: v3
+	Env   []string // Additional environment variables for this platform's build. Ex: GOEXPERIMENT=...
+}
+
+// Returns the platform in the form used by go tool dist list with the variant if any. Ex: linux/arm/7
+func (platform Platform) String() string {
+	name := platform.OS + "/" + platform.Arch
+	if variant := platform.Variant(); variant != "" {
+		name += "/" + variant
+	}
+	return name
+}
+
+// Returns GOARM or GOAMD64 depending on the architecture.
+func (platform Platform) Variant() string {
+	switch platform.Arch {
+	case "arm":
+		return platform.Arm
+	case "amd64":
+		return platform.Amd64
+	}
+	return ""
+}
+
+// Returns the environment the platform is built with. Cgo is always disabled.
+func (platform Platform) env() []string {
+	env := []string{"CGO_ENABLED=0", "GOOS=" + platform.OS, "GOARCH=" + platform.Arch}
+	// Like Variant, the fields only apply to their architecture so the environment agrees with output names
+	if variant := platform.Variant(); variant != "" && platform.Arch == "arm" {
+		env = append(env, "GOARM="+variant)
+	} else if variant != "" && platform.Arch == "amd64" {
+		env = append(env, "GOAMD64="+variant)
+	}
+	return append(env, platform.Env...)
+}
+
+// Data [GoBuild].Output templates are executed with.
+type platformOutput struct {
+	Name    string // Base name of the package being built.
+	OS      string
+	Arch    string
+	Variant string // GOARM or GOAMD64 if set.
+	Ext     string // .exe on windows, otherwise empty.
+}
+
+// A binary produced by a cross compiled [GoBuild].
+type Artifact struct {
+	Name     string // Name of the program. Ex: gopher
+	Platform Platform
+	Path     string
+	Size     int64 // Bytes
+}
+
+// Returns the [Artifact]s of the last cross compiled [GoBuild] that ran in this iteration.
+func (gopher *Gopher) Artifacts() ([]Artifact, bool) {
+	return ResultAs[[]Artifact](gopher, ArtifactsKey)
+}
+
+// Builds the package once for every platform.
+func (build *GoBuild) runPlatforms(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Go Build")
+	printer.Start()
+	if len(build.Packages) > 1 {
+		err := errors.New("only one package may be built for several platforms")
+		printer.Done(err)
+		return err
+	}
+	name, err := build.binaryName(ctx, *args)
+	if err != nil {
+		printer.Done(err)
+		return err
+	}
+	outputs, err := build.platformOutputs(name)
+	if err != nil {
+		printer.Done(err)
+		return err
+	}
+	// Every platform is stamped with the same version and date
+	flags := build.flags(build.version(ctx))
+	fmt.Fprintf(printer, "building for %d platforms\n", len(build.Platforms))
+	printer.Done(nil)
+
+	// Indexed by platform so the builds do not need to synchronize
+	artifacts := make([]*Artifact, len(build.Platforms))
+	runners := make([]Runner, 0, len(build.Platforms))
+	for i, platform := range build.Platforms {
+		runners = append(runners, RunnerFunc(func(ctx context.Context, gopher *Gopher) error {
+			printer := pretty.New(gopher.Stdout, "Go Build "+platform.String())
+			printer.Start()
+
+			cmdArgs := append([]string{"build", "-o", outputs[i]}, flags...)
+			cmdArgs = append(cmdArgs, build.Packages...)
+			runner := &ExecCmdRunner{
+				Name: gopher.GoConfig.GoBin,
+				Args: cmdArgs,
+				Env:  platform.env(),
+			}
+			copied := *gopher
+			copied.Stdout = pretty.NewIndentedWriter(printer, "  ")
+			err := runner.Run(ctx, &copied)
+			if err == nil {
+				var info os.FileInfo
+				if info, err = os.Stat(outputs[i]); err == nil {
+					artifacts[i] = &Artifact{Name: name, Platform: platform, Path: outputs[i], Size: info.Size()}
+				}
+			}
+			printer.Done(err)
+			return err
+		}))
+	}
+	parallel := &ParallelRunner{
+		Runners: runners,
+		Workers: build.Workers,
+	}
+	if err := parallel.Run(ctx, args); err != nil {
+		return err
+	}
+
+	built := make([]Artifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		built = append(built, *artifact)
+	}
+	args.SetResult(ArtifactsKey, built)
+
+	printer = pretty.New(args.Stdout, "Go Build Artifacts")
+	printer.Start()
+	writeArtifactTable(pretty.NewIndentedWriter(printer, "  "), built)
+	return printer.Done(nil)
+}
+
+// Executes the output template for every platform, rejecting platforms that would overwrite each other.
+func (build *GoBuild) platformOutputs(name string) ([]string, error) {
+	output := build.Output
+	if output == "" {
+		output = DefaultPlatformOutput
+	}
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(output)
+	if err != nil {
+		return nil, fmt.Errorf("parsing output template: %w", err)
+	}
+
+	seen := map[string]Platform{}
+	outputs := make([]string, 0, len(build.Platforms))
+	for _, platform := range build.Platforms {
+		if platform.OS == "" || platform.Arch == "" {
+			return nil, fmt.Errorf("platform %q: OS and Arch are required", platform)
+		}
+		data := platformOutput{
+			Name:    name,
+			OS:      platform.OS,
+			Arch:    platform.Arch,
+			Variant: platform.Variant(),
+		}
+		if platform.OS == "windows" {
+			data.Ext = ".exe"
+		}
+		var builder strings.Builder
+		if err := tmpl.Execute(&builder, data); err != nil {
+			return nil, fmt.Errorf("executing output template: %w", err)
+		}
+		if other, ok := seen[builder.String()]; ok {
+			return nil, fmt.Errorf("platforms %s and %s both output %s", other, platform, builder.String())
+		}
+		seen[builder.String()] = platform
+		outputs = append(outputs, builder.String())
+	}
+	return outputs, nil
+}
+
+// Returns the name go build would give the binary: the last element of the package's import path.
+func (build *GoBuild) binaryName(ctx context.Context, gopher Gopher) (string, error) {
+	pkg := "."
+	if len(build.Packages) == 1 {
+		pkg = build.Packages[0]
+	}
+	var output bytes.Buffer
+	gopher.Stdout = &output
+	runner := &ExecCmdRunner{
+		Name: gopher.GoConfig.GoBin,
+		Args: []string{"list", "-f", "{{.ImportPath}}", pkg},
+	}
+	if err := runner.Run(ctx, &gopher); err != nil {
+		return "", fmt.Errorf("listing package: %w: %s", err, output.String())
+	}
+	return path.Base(strings.TrimSpace(output.String())), nil
+}
+
+// Writes a table of the artifacts and their sizes.
+func writeArtifactTable(stdout io.Writer, artifacts []Artifact) {
+	width := len("ARTIFACT")
+	for _, artifact := range artifacts {
+		width = max(width, len(artifact.Path))
+	}
+	platformWidth := len("PLATFORM")
+	for _, artifact := range artifacts {
+		platformWidth = max(platformWidth, len(artifact.Platform.String()))
+	}
+	fmt.Fprintf(stdout, "%-*s  %-*s  %9s\n", width, "ARTIFACT", platformWidth, "PLATFORM", "SIZE")
+	for _, artifact := range artifacts {
+		fmt.Fprintf(stdout, "%-*s  %-*s  %9s\n", width, artifact.Path, platformWidth, artifact.Platform, formatSize(artifact.Size))
+	}
+}
+
+// Formats bytes using binary units. Ex: 1.5 MiB
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / 1024
+	for _, suffix := range []string{"KiB", "MiB"} {
+		if value < 1024 {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= 1024
+	}
+	return fmt.Sprintf("%.1f GiB", value)
+}
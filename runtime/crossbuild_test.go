@@ -0,0 +1,135 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoBuildPlatformOutputs(t *testing.T) {
+	tests := []struct {
+		Build    GoBuild
+		Expected []string
+		ErrorMsg string
+	}{
+		{
+			Build: GoBuild{Platforms: []Platform{
+				{OS: "linux", Arch: "amd64"},
+				{OS: "windows", Arch: "arm64"},
+			}},
+			Expected: []string{"target/gofmt_linux_amd64", "target/gofmt_windows_arm64.exe"},
+		},
+		{
+			Build: GoBuild{
+				Output: "dist/{{.Name}}-{{.OS}}-{{.Arch}}{{with .Variant}}v{{.}}{{end}}{{.Ext}}",
+				Platforms: []Platform{
+					{OS: "linux", Arch: "arm", Arm: "6"},
+					{OS: "linux", Arch: "arm", Arm: "7"},
+				},
+			},
+			Expected: []string{"dist/gofmt-linux-armv6", "dist/gofmt-linux-armv7"},
+		},
+		{
+			Build: GoBuild{Platforms: []Platform{
+				{OS: "linux", Arch: "arm", Arm: "6"},
+				{OS: "linux", Arch: "arm", Arm: "7"},
+			}},
+			ErrorMsg: "platforms linux/arm/6 and linux/arm/7 both output target/gofmt_linux_arm",
+		},
+		{
+			Build:    GoBuild{Output: "{{.Missing}}", Platforms: []Platform{{OS: "linux", Arch: "amd64"}}},
+			ErrorMsg: "executing output template",
+		},
+		{
+			Build:    GoBuild{Platforms: []Platform{{OS: "linux"}}},
+			ErrorMsg: "OS and Arch are required",
+		},
+	}
+	for _, test := range tests {
+		outputs, err := test.Build.platformOutputs("gofmt")
+		if test.ErrorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), test.ErrorMsg) {
+				t.Fatalf("got: %v expected error containing: %s", err, test.ErrorMsg)
+			}
+			continue
+		} else if err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+		if strings.Join(outputs, " ") != strings.Join(test.Expected, " ") {
+			t.Fatalf("got: %v expected: %v", outputs, test.Expected)
+		}
+	}
+}
+
+func TestPlatformEnv(t *testing.T) {
+	tests := []struct {
+		Platform Platform
+		Expected string
+	}{
+		{Platform: Platform{OS: "linux", Arch: "arm", Arm: "7"}, Expected: "CGO_ENABLED=0 GOOS=linux GOARCH=arm GOARM=7"},
+		{Platform: Platform{OS: "linux", Arch: "amd64", Amd64: "v3"}, Expected: "CGO_ENABLED=0 GOOS=linux GOARCH=amd64 GOAMD64=v3"},
+		// Variants of other architectures are ignored like in the output names
+		{Platform: Platform{OS: "linux", Arch: "amd64", Arm: "7"}, Expected: "CGO_ENABLED=0 GOOS=linux GOARCH=amd64"},
+		{Platform: Platform{OS: "linux", Arch: "arm64", Amd64: "v3", Env: []string{"GOEXPERIMENT=x"}}, Expected: "CGO_ENABLED=0 GOOS=linux GOARCH=arm64 GOEXPERIMENT=x"},
+	}
+	for _, test := range tests {
+		if env := strings.Join(test.Platform.env(), " "); env != test.Expected {
+			t.Fatalf("%s: got: %s expected: %s", test.Platform, env, test.Expected)
+		}
+	}
+}
+
+func TestGoBuildPlatforms(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross compiles")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":  "module example.com/hello\n\ngo 1.22\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+
+	var stdout strings.Builder
+	gopher := &Gopher{GoConfig: GoConfig{GoBin: "go"}, Stdout: &stdout}
+	build := GoBuild{
+		Platforms: []Platform{
+			{OS: "linux", Arch: "amd64", Amd64: "v2"},
+			{OS: "windows", Arch: "amd64"},
+		},
+		Workers: 2,
+	}
+	if err := build.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+
+	artifacts, ok := gopher.Artifacts()
+	if !ok || len(artifacts) != 2 {
+		t.Fatalf("got: %v expected 2 artifacts", artifacts)
+	}
+	for i, expected := range []string{"target/hello_linux_amd64", "target/hello_windows_amd64.exe"} {
+		if artifacts[i].Path != expected || artifacts[i].Name != "hello" || artifacts[i].Size == 0 {
+			t.Fatalf("got: %+v expected: %s", artifacts[i], expected)
+		}
+		if _, err := os.Stat(filepath.Join(dir, expected)); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+		if !strings.Contains(stdout.String(), expected) {
+			t.Fatalf("expected %s in the summary: %s", expected, stdout.String())
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		12:              "12 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for size, expected := range tests {
+		if formatted := formatSize(size); formatted != expected {
+			t.Fatalf("got: %s expected: %s", formatted, expected)
+		}
+	}
+}
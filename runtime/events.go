@@ -1,24 +1,111 @@
 package runtime
 
 import (
-	"github.com/ohhfishal/nibbles/assert"
+	"cmp"
+	"context"
 	"iter"
+	"slices"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ohhfishal/nibbles/assert"
 )
 
-// A sequence that yields when work is to be done.
+/*
+A sequence that yields when work is to be done.
+The yielded value describes what triggered the event, such as a [ChangeSet], and is
+available to runners using [EventValue].
+*/
 type Event iter.Seq[any]
 
+// A file that changed.
+type FileChange struct {
+	Path string      // Path of the file as reported by the watcher.
+	Op   fsnotify.Op // All operations seen on the file, OR'd together.
+}
+
+// The files changed during a burst of changes, yielded by [OnFileChange].
+type ChangeSet struct {
+	Changes    []FileChange // Changed files sorted by path.
+	Time       time.Time    // When the latest change was seen.
+	Incomplete bool         // True if the watcher dropped events so any file may have changed.
+}
+
+// Returns the paths of every changed file.
+func (changes ChangeSet) Paths() []string {
+	paths := make([]string, 0, len(changes.Changes))
+	for _, change := range changes.Changes {
+		paths = append(paths, change.Path)
+	}
+	return paths
+}
+
+// Returns a set containing the changes of both sets. Operations on the same path are combined.
+func (changes ChangeSet) Merge(other ChangeSet) ChangeSet {
+	merged := ChangeSet{
+		Changes:    slices.Clone(changes.Changes),
+		Time:       changes.Time,
+		Incomplete: changes.Incomplete || other.Incomplete,
+	}
+	if other.Time.After(merged.Time) {
+		merged.Time = other.Time
+	}
+	for _, change := range other.Changes {
+		index, found := slices.BinarySearchFunc(merged.Changes, change.Path, func(change FileChange, path string) int {
+			return cmp.Compare(change.Path, path)
+		})
+		if found {
+			merged.Changes[index].Op |= change.Op
+			continue
+		}
+		merged.Changes = slices.Insert(merged.Changes, index, change)
+	}
+	return merged
+}
+
+type eventKey struct{}
+
+// Returns the value yielded by the [Event] that triggered the current [Gopher.Run] iteration.
+func EventValue(ctx context.Context) any {
+	return ctx.Value(eventKey{})
+}
+
+// Returns the [ChangeSet] that triggered the current [Gopher.Run] iteration if any.
+func FileChanges(ctx context.Context) (ChangeSet, bool) {
+	changes, ok := EventValue(ctx).(ChangeSet)
+	return changes, ok
+}
+
+func withEventValue(ctx context.Context, value any) context.Context {
+	return context.WithValue(ctx, eventKey{}, value)
+}
+
+/*
+Combines the values of two coalesced events. [ChangeSet]s are merged, otherwise the latest wins.
+Other values, such as the nil of [Now], win over a [ChangeSet] since they mean everything
+may have changed, so the full iteration is not narrowed down to the files of the later event.
+*/
+func mergeEventValues(old any, latest any) any {
+	oldChanges, oldOk := old.(ChangeSet)
+	latestChanges, latestOk := latest.(ChangeSet)
+	if oldOk && latestOk {
+		return oldChanges.Merge(latestChanges)
+	} else if latestOk {
+		return old
+	}
+	return latest
+}
+
 // Returns a sequence that yields once immediately, then returns the passed in event's sequence.
 func NowAnd(when Event) Event {
 	return func(yield func(any) bool) {
 		for range Now() {
 			if !yield(nil) {
-				break
+				return
 			}
 		}
-		for range when {
-			if !yield(nil) {
+		for value := range when {
+			if !yield(value) {
 				return
 			}
 		}
@@ -46,17 +133,28 @@ func AfterEvery(duration time.Duration) Event {
 }
 
 /*
-Returns an Event that yields whenever a file of the matching extension is modified.
-Interval is the minimum time between two events.
+Returns an Event that yields a [ChangeSet] whenever a file of the matching extension is modified.
+Changes are collected until the burst settles and interval is the minimum time between two events.
+The current directory is watched ignoring [DefaultExclude] and paths in .gitignore files.
+Use [OnFileChangeWith] for more control.
 Panics if there is an error. (Which signifies the os is probably suffering).
 */
 func OnFileChange(interval time.Duration, extensions ...string) Event {
-	// TODO: Support options
-	cache := &fileCache{
+	return OnFileChangeWith(WatchOptions{
 		Interval:   interval,
 		Extensions: extensions,
-	}
-	event, err := cache.Event()
+		GitIgnore:  true,
+	})
+}
+
+/*
+Returns an Event that yields a [ChangeSet] whenever a file matching the options is modified.
+Directories created while watching are watched as well.
+Panics if there is an error. (Which signifies the os is probably suffering).
+Use [WatchFiles] to handle the error instead.
+*/
+func OnFileChangeWith(options WatchOptions) Event {
+	event, err := WatchFiles(options)
 	assert.Nil(err, "filecache getting an event: %w", err)
 	assert.True(
 		event != nil,
@@ -64,3 +162,14 @@ func OnFileChange(interval time.Duration, extensions ...string) Event {
 	)
 	return event
 }
+
+/*
+Same as [OnFileChangeWith] but returns an error if the watcher can not be set up.
+Errors that happen while watching are printed as warnings instead of stopping the event.
+*/
+func WatchFiles(options WatchOptions) (Event, error) {
+	cache := &fileCache{
+		WatchOptions: options,
+	}
+	return cache.Event()
+}
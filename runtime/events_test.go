@@ -0,0 +1,198 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestChangeSetMerge(t *testing.T) {
+	now := time.Now()
+	left := ChangeSet{
+		Changes: []FileChange{
+			{Path: "a.go", Op: fsnotify.Create},
+			{Path: "c.go", Op: fsnotify.Write},
+		},
+		Time: now,
+	}
+	right := ChangeSet{
+		Changes: []FileChange{
+			{Path: "b.go", Op: fsnotify.Write},
+			{Path: "a.go", Op: fsnotify.Write},
+		},
+		Time: now.Add(time.Second),
+	}
+
+	merged := left.Merge(right)
+	expected := []FileChange{
+		{Path: "a.go", Op: fsnotify.Create | fsnotify.Write},
+		{Path: "b.go", Op: fsnotify.Write},
+		{Path: "c.go", Op: fsnotify.Write},
+	}
+	if !slices.Equal(merged.Changes, expected) {
+		t.Fatalf("got: %v expected: %v", merged.Changes, expected)
+	} else if !merged.Time.Equal(right.Time) {
+		t.Fatalf("got time: %s expected: %s", merged.Time, right.Time)
+	} else if len(left.Changes) != 2 {
+		t.Fatalf("merge modified the receiver: %v", left.Changes)
+	}
+}
+
+func TestMergeEventValues(t *testing.T) {
+	left := ChangeSet{Changes: []FileChange{{Path: "a.go", Op: fsnotify.Write}}}
+	right := ChangeSet{Changes: []FileChange{{Path: "b.go", Op: fsnotify.Write}}}
+	tests := []struct {
+		Old      any
+		Latest   any
+		Expected any
+	}{
+		// A full run from Now must not be narrowed down to the files saved while it ran
+		{Old: nil, Latest: right, Expected: nil},
+		{Old: left, Latest: nil, Expected: nil},
+		{Old: "tick", Latest: right, Expected: "tick"},
+		{Old: nil, Latest: "tick", Expected: "tick"},
+	}
+	for _, test := range tests {
+		if merged := mergeEventValues(test.Old, test.Latest); merged != test.Expected {
+			t.Fatalf("merging %v and %v got: %v expected: %v", test.Old, test.Latest, merged, test.Expected)
+		}
+	}
+
+	merged, ok := mergeEventValues(left, right).(ChangeSet)
+	if !ok || len(merged.Changes) != 2 {
+		t.Fatalf("got: %v expected the merged changes", merged)
+	}
+}
+
+func TestFileChangeCoalescesBurst(t *testing.T) {
+	dir := t.TempDir()
+	cache := &fileCache{
+		WatchOptions: WatchOptions{
+			Roots:      []string{dir},
+			Extensions: []string{".go"},
+		},
+	}
+	event, err := cache.Event()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	values := make(chan any)
+	go func() {
+		for value := range event {
+			values <- value
+			return
+		}
+	}()
+
+	for _, name := range []string{"a.go", "b.go", "ignored.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a"), 0644); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+	}
+
+	select {
+	case value := <-values:
+		changes, ok := value.(ChangeSet)
+		if !ok {
+			t.Fatalf("got: %T expected: ChangeSet", value)
+		}
+		expected := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")}
+		if paths := changes.Paths(); !slices.Equal(paths, expected) {
+			t.Fatalf("got: %v expected: %v", paths, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestFileChangeWatchesNewDirectories(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("generated/\n"), 0644); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	cache := &fileCache{
+		WatchOptions: WatchOptions{
+			Roots:      []string{dir},
+			Extensions: []string{".go"},
+			GitIgnore:  true,
+		},
+	}
+	event, err := cache.Event()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	values := make(chan any)
+	go func() {
+		for value := range event {
+			values <- value
+		}
+	}()
+
+	for _, name := range []string{"target", "generated", "pkg"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+	}
+	// Give the watcher time to add the new directories
+	time.Sleep(250 * time.Millisecond)
+	for _, name := range []string{"target/a.go", "generated/b.go", "pkg/c.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a"), 0644); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+	}
+
+	select {
+	case value := <-values:
+		changes := value.(ChangeSet)
+		expected := []string{filepath.Join(dir, "pkg", "c.go")}
+		if paths := changes.Paths(); !slices.Equal(paths, expected) {
+			t.Fatalf("got: %v expected: %v", paths, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestFileChangeRecoversFromErrors(t *testing.T) {
+	dir := t.TempDir()
+	var warnings strings.Builder
+	cache := &fileCache{
+		WatchOptions: WatchOptions{
+			Roots:    []string{dir},
+			Warnings: &warnings,
+		},
+	}
+	watcher, err := cache.newWatcher()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	defer watcher.Close()
+
+	events := make(chan fsnotify.Event)
+	errs := make(chan error, 2)
+	errs <- errors.New("watched directory went away")
+	errs <- fsnotify.ErrEventOverflow
+	close(errs)
+
+	changes, ok := cache.collect(events, errs, time.Time{})
+	if !ok {
+		t.Fatal("collect stopped after an error")
+	} else if !changes.Incomplete {
+		t.Fatalf("got: %+v expected an incomplete change set", changes)
+	} else if !cache.watched[dir] {
+		t.Fatalf("root is not watched after rescanning: %v", cache.watched)
+	}
+	for _, msg := range []string{"watched directory went away", "rescanning"} {
+		if !strings.Contains(warnings.String(), msg) {
+			t.Errorf(`got warnings: "%s" expected: "%s"`, warnings.String(), msg)
+		}
+	}
+}
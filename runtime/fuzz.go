@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	goruntime "runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+var _ Runner = &GoFuzz{}
+
+/*
+[GoFuzz] implements the [Runner] interface and exec's `go test -fuzz` for every fuzz target
+found in the packages. Failing inputs written to testdata/fuzz are reported as failures
+along with the command to reproduce them. Fuzzing is unbounded so it is best suited for a
+target scheduled using [AfterEvery].
+*/
+type GoFuzz struct {
+	Packages []string      // Packages to search for fuzz targets. If empty, defaults to ["./..."].
+	Flags    []string      // Any additional flags to be passed to go test.
+	Pattern  string        // Only fuzz targets matching the regexp.
+	FuzzTime time.Duration // Time to fuzz each target for. If 0, defaults to 30s. Effectively go test -fuzztime.
+	// Number of targets fuzzed at once, splitting the CPUs between them. If <= 1, targets are fuzzed one at a time
+	// using every CPU.
+	Workers int
+}
+
+// A fuzz target of a package.
+type fuzzTarget struct {
+	Name       string
+	ImportPath string
+	Dir        string
+	FuzzTime   time.Duration
+	Parallel   int
+	Flags      []string
+}
+
+func (fuzz *GoFuzz) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Go Fuzz")
+	printer.Start()
+	targets, err := fuzz.targets(ctx, *args)
+	if err == nil && len(targets) == 0 {
+		fmt.Fprintln(printer, "no fuzz targets")
+	}
+	printer.Done(err)
+	if err != nil || len(targets) == 0 {
+		return err
+	}
+
+	workers := max(fuzz.Workers, 1)
+	runners := make([]Runner, 0, len(targets))
+	for _, target := range targets {
+		target.Parallel = max(goruntime.NumCPU()/workers, 1)
+		runners = append(runners, target)
+	}
+	parallel := &ParallelRunner{
+		Runners:   runners,
+		Workers:   workers,
+		KeepGoing: true,
+	}
+	return parallel.Run(ctx, args)
+}
+
+// Finds the fuzz targets in the packages using `go test -list`.
+func (fuzz *GoFuzz) targets(ctx context.Context, gopher Gopher) ([]*fuzzTarget, error) {
+	var pattern *regexp.Regexp
+	if fuzz.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(fuzz.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	packages := fuzz.Packages
+	if len(packages) == 0 {
+		packages = append(packages, "./...")
+	}
+	list, err := listPackages(ctx, gopher.GoConfig.GoBin, packages)
+	if err != nil {
+		return nil, fmt.Errorf("listing packages: %w", err)
+	}
+	dirs := map[string]string{}
+	for _, pkg := range list {
+		dirs[pkg.ImportPath] = pkg.Dir
+	}
+
+	var output bytes.Buffer
+	gopher.Stdout = &output
+	runner := &ExecCmdRunner{
+		Name: gopher.GoConfig.GoBin,
+		Args: append([]string{"test", "-json", "-list", "^Fuzz"}, packages...),
+	}
+	runErr := runner.Run(ctx, &gopher)
+	report, err := ParseTestReport(&output)
+	if runErr != nil || err != nil {
+		var summary strings.Builder
+		report.WriteSummary(&summary)
+		return nil, fmt.Errorf("listing fuzz targets: %w: %s", errors.Join(runErr, err), summary.String())
+	}
+
+	fuzzTime := fuzz.FuzzTime
+	if fuzzTime == 0 {
+		fuzzTime = 30 * time.Second
+	}
+	var targets []*fuzzTarget
+	for _, pkg := range report.Packages {
+		for line := range strings.Lines(pkg.Output) {
+			name := strings.TrimSpace(line)
+			if !strings.HasPrefix(name, "Fuzz") || strings.ContainsAny(name, " \t") {
+				continue
+			}
+			if pattern != nil && !pattern.MatchString(name) {
+				continue
+			}
+			targets = append(targets, &fuzzTarget{
+				Name:       name,
+				ImportPath: pkg.Name,
+				Dir:        dirs[pkg.Name],
+				FuzzTime:   fuzzTime,
+				Flags:      fuzz.Flags,
+			})
+		}
+	}
+	return targets, nil
+}
+
+// Directory the go command writes failing inputs of the target to.
+func (target *fuzzTarget) corpus() string {
+	return filepath.Join(target.Dir, "testdata", "fuzz", target.Name)
+}
+
+// Returns the names of the files in the target's corpus.
+func (target *fuzzTarget) inputs() map[string]bool {
+	inputs := map[string]bool{}
+	entries, _ := os.ReadDir(target.corpus())
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			inputs[entry.Name()] = true
+		}
+	}
+	return inputs
+}
+
+func (target *fuzzTarget) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, fmt.Sprintf("Go Fuzz %s %s", target.ImportPath, target.Name))
+	printer.Start()
+
+	before := target.inputs()
+	cmdArgs := []string{
+		"test",
+		"-run=^$",
+		"-fuzz=^" + target.Name + "$",
+		"-fuzztime=" + target.FuzzTime.String(),
+		"-parallel=" + strconv.Itoa(target.Parallel),
+	}
+	cmdArgs = append(cmdArgs, target.Flags...)
+	cmdArgs = append(cmdArgs, target.ImportPath)
+
+	var output bytes.Buffer
+	gopher := *args
+	gopher.Stdout = &output
+	runner := &ExecCmdRunner{
+		Name: args.GoConfig.GoBin,
+		Args: cmdArgs,
+	}
+	err := runner.Run(ctx, &gopher)
+
+	var failures []error
+	for _, input := range slices.Sorted(maps.Keys(target.inputs())) {
+		if before[input] {
+			continue
+		}
+		failures = append(failures, fmt.Errorf(
+			"%s found a failing input, to reproduce run: go test -run=^%s/%s$ %s",
+			target.Name, target.Name, input, target.ImportPath,
+		))
+	}
+	if len(failures) > 0 {
+		err = errors.Join(failures...)
+	}
+	if err != nil {
+		writeTestOutput(printer, output.String())
+	}
+	printer.Done(err)
+	return err
+}
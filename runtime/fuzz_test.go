@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoFuzz(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the fuzzer")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":  "module example.com/fuzz\n\ngo 1.22\n",
+		"fuzz.go": "package fuzz\n",
+		"fuzz_test.go": `package fuzz
+
+import "testing"
+
+func FuzzFail(f *testing.F) {
+	f.Add(0)
+	f.Fuzz(func(t *testing.T, n int) {
+		if n > 100 || n < -100 {
+			t.Fatal("out of range")
+		}
+	})
+}
+
+func FuzzPass(f *testing.F) {
+	f.Add(0)
+	f.Fuzz(func(t *testing.T, n int) {})
+}
+
+func TestNotFuzz(t *testing.T) {}
+`,
+	})
+	gopher := &Gopher{GoConfig: GoConfig{GoBin: "go"}}
+
+	var stdout strings.Builder
+	gopher.Stdout = &stdout
+	if err := (&GoFuzz{Pattern: "Pass", FuzzTime: time.Second}).Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "FuzzPass") || strings.Contains(stdout.String(), "FuzzFail") {
+		t.Fatalf("expected only FuzzPass to run: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	err := (&GoFuzz{FuzzTime: 2 * time.Second, Workers: 2}).Run(context.Background(), gopher)
+	if err == nil || !strings.Contains(err.Error(), "FuzzFail found a failing input, to reproduce run: go test -run=^FuzzFail/") {
+		t.Fatalf("got: %v expected a failing input: %s", err, stdout.String())
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "testdata", "fuzz", "FuzzFail"))
+	if len(entries) != 1 {
+		t.Fatalf("got: %d expected the failing input to be written", len(entries))
+	}
+	if strings.Index(stdout.String(), "FuzzFail") > strings.Index(stdout.String(), "FuzzPass") {
+		t.Fatalf("expected output in the order targets were found: %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := (&GoFuzz{Pattern: "Missing"}).Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if !strings.Contains(stdout.String(), "no fuzz targets") {
+		t.Fatalf("expected no fuzz targets: %s", stdout.String())
+	}
+}
@@ -1,10 +1,13 @@
 package runtime
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type GitHook string
@@ -31,3 +34,22 @@ func InstallGitHook(stdout io.Writer, hook GitHook, content string) error {
 	}
 	return nil
 }
+
+// Returns the commit checked out in dir and whether the working tree has uncommitted changes.
+func gitCommit(ctx context.Context, dir string) (commit string, dirty bool, err error) {
+	cmd := CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", false, fmt.Errorf("getting commit: %w", err)
+	}
+	commit = strings.TrimSpace(string(output))
+
+	cmd = CommandContext(ctx, "git", "status", "--porcelain")
+	cmd.Dir = dir
+	output, err = cmd.Output()
+	if err != nil {
+		return "", false, fmt.Errorf("getting status: %w", err)
+	}
+	return commit, len(bytes.TrimSpace(output)) > 0, nil
+}
@@ -1,11 +1,16 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ohhfishal/gopher/pretty"
 )
@@ -15,6 +20,7 @@ var _ Runner = &GoTest{}
 var _ Runner = &GoFormat{}
 var _ Runner = &GoVet{}
 var _ Runner = &GoModTidy{}
+var _ Validator = &GoTest{}
 
 /*
 [GoBuild] implements the [Runner] interface and exec's `go build`.
@@ -23,13 +29,45 @@ type GoBuild struct {
 	Output   string   // Binary file produced. Effectively go build -o.
 	Flags    []string // Any additional flags to be passed to go build
 	Packages []string // Positional args. If empty, defaults to ["./..."].
+	// Builds a binary that writes coverage data to $GOCOVERDIR when run. See [GoCoverage].CoverDirs.
+	// Effectively go build -cover.
+	Cover bool
+	// If not empty, builds the package once for each platform with cgo disabled. Output is then a
+	// [text/template] of the file names, defaulting to [DefaultPlatformOutput]. See [Gopher.Artifacts].
+	Platforms []Platform
+	Workers   int // Maximum number of platforms built at once. If <= 0, defaults to the number of CPUs.
+	// Variables to set to the version, commit and build date from git. Merged with any -ldflags in Flags.
+	Stamp Stamp
 }
 
 /*
 [GoTest] implements the [Runner] interface and exec's `go test`.
+Options are checked using [GoTest.Validate] once before [Gopher.RunWith] starts its loop,
+and again before any command is run.
 */
 type GoTest struct {
-	Packages []string // Positional args. If empty, defaults to ["./..."].
+	Packages    []string      // Positional args. If empty, defaults to ["./..."].
+	Flags       []string      // Any additional flags to be passed to go test.
+	Race        bool          // Enables the race detector. Effectively go test -race.
+	RunPattern  string        // Only run tests matching the pattern. Effectively go test -run.
+	SkipPattern string        // Skip tests matching the pattern. Effectively go test -skip.
+	Count       int           // Times to run each test. If 0, uses the go default. Effectively go test -count.
+	Timeout     time.Duration // Panics the test binary after the duration. Effectively go test -timeout.
+	Shuffle     string        // "on", "off" or a seed used to randomize test order. Effectively go test -shuffle.
+	Short       bool          // Tells long running tests to shorten their run time. Effectively go test -short.
+	Tags        []string      // Build tags. Effectively go test -tags.
+	CPU         []int         // GOMAXPROCS values to run tests with. Effectively go test -cpu.
+	FailFast    bool          // Do not start new tests after the first failure. Effectively go test -failfast.
+	// Writes a coverage profile to the file. Effectively go test -coverprofile.
+	CoverProfile string
+	CoverMode    string   // "set", "count" or "atomic". Effectively go test -covermode.
+	CoverPkg     []string // Packages coverage is recorded for. Effectively go test -coverpkg.
+	// When true and the iteration was triggered by [OnFileChange], only the packages containing
+	// changed files and the packages that transitively import them are tested. Everything is
+	// tested if go.mod or go.sum changed, so watch ".mod" and ".sum" files for that to apply.
+	Affected bool
+	// Writes a JUnit XML report of the results to the file. Written even if tests fail.
+	JUnitReport string
 }
 
 /*
@@ -63,10 +101,13 @@ func runGoTool(ctx context.Context, stdout io.Writer, gopher Gopher, cmdArgs []s
 }
 
 func (build *GoBuild) Run(ctx context.Context, args *Gopher) error {
+	if len(build.Platforms) > 0 {
+		return build.runPlatforms(ctx, args)
+	}
 	printer := pretty.New(args.Stdout, "Go Build")
 	printer.Start()
 
-	cmdArgs := append([]string{"build"}, build.Flags...)
+	cmdArgs := append([]string{"build"}, build.flags(build.version(ctx))...)
 	if build.Output != "" {
 		cmdArgs = append(cmdArgs, "-o", build.Output)
 	}
@@ -77,22 +118,188 @@ func (build *GoBuild) Run(ctx context.Context, args *Gopher) error {
 	return err
 }
 
+// Returns the flags passed to go build for the options.
+func (build *GoBuild) flags(info VersionInfo) []string {
+	var flags []string
+	if build.Cover {
+		flags = append(flags, "-cover")
+	}
+	if build.Stamp.enabled() {
+		return append(flags, mergeLDFlags(build.Flags, build.Stamp.ldflags(info))...)
+	}
+	return append(flags, build.Flags...)
+}
+
+// Returns the version information to stamp, only calling git if needed.
+func (build *GoBuild) version(ctx context.Context) VersionInfo {
+	if !build.Stamp.enabled() {
+		return VersionInfo{}
+	}
+	return GitVersion(ctx, "")
+}
+
+// Returns an error if any of the options would be rejected by go test.
+func (test *GoTest) Validate() error {
+	var errs []error
+	if test.Count < 0 {
+		errs = append(errs, fmt.Errorf("count must not be negative: %d", test.Count))
+	}
+	if test.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must not be negative: %s", test.Timeout))
+	}
+	if _, err := strconv.ParseInt(test.Shuffle, 10, 64); err != nil && !slices.Contains([]string{"", "on", "off"}, test.Shuffle) {
+		errs = append(errs, fmt.Errorf(`shuffle must be "on", "off" or an integer seed: %q`, test.Shuffle))
+	}
+	if !slices.Contains([]string{"", "set", "count", "atomic"}, test.CoverMode) {
+		errs = append(errs, fmt.Errorf(`cover mode must be "set", "count" or "atomic": %q`, test.CoverMode))
+	}
+	for _, cpu := range test.CPU {
+		if cpu <= 0 {
+			errs = append(errs, fmt.Errorf("cpu values must be positive: %d", cpu))
+		}
+	}
+	for i, pattern := range []string{test.RunPattern, test.SkipPattern} {
+		// Like go test, each slash separated element is matched against one level of subtests
+		for element := range strings.SplitSeq(pattern, "/") {
+			if _, err := regexp.Compile(element); err != nil {
+				errs = append(errs, fmt.Errorf("%s pattern: %w", []string{"run", "skip"}[i], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the flags passed to go test for the options.
+func (test *GoTest) flags() []string {
+	var flags []string
+	if test.Race {
+		flags = append(flags, "-race")
+	}
+	if test.RunPattern != "" {
+		flags = append(flags, "-run", test.RunPattern)
+	}
+	if test.SkipPattern != "" {
+		flags = append(flags, "-skip", test.SkipPattern)
+	}
+	if test.Count > 0 {
+		flags = append(flags, "-count", strconv.Itoa(test.Count))
+	}
+	if test.Timeout > 0 {
+		flags = append(flags, "-timeout", test.Timeout.String())
+	}
+	if test.Shuffle != "" {
+		flags = append(flags, "-shuffle", test.Shuffle)
+	}
+	if test.Short {
+		flags = append(flags, "-short")
+	}
+	if len(test.Tags) > 0 {
+		flags = append(flags, "-tags", strings.Join(test.Tags, ","))
+	}
+	if len(test.CPU) > 0 {
+		cpus := make([]string, 0, len(test.CPU))
+		for _, cpu := range test.CPU {
+			cpus = append(cpus, strconv.Itoa(cpu))
+		}
+		flags = append(flags, "-cpu", strings.Join(cpus, ","))
+	}
+	if test.FailFast {
+		flags = append(flags, "-failfast")
+	}
+	if test.CoverProfile != "" {
+		flags = append(flags, "-coverprofile", test.CoverProfile)
+	}
+	if test.CoverMode != "" {
+		flags = append(flags, "-covermode", test.CoverMode)
+	}
+	if len(test.CoverPkg) > 0 {
+		flags = append(flags, "-coverpkg", strings.Join(test.CoverPkg, ","))
+	}
+	return append(flags, test.Flags...)
+}
+
 func (test *GoTest) Run(ctx context.Context, args *Gopher) error {
 	printer := pretty.New(args.Stdout, "Go Test")
 	printer.Start()
+	if err := test.Validate(); err != nil {
+		printer.Done(err)
+		return fmt.Errorf("invalid options: %w", err)
+	}
 
 	packages := test.Packages
 	if len(packages) == 0 {
 		packages = append(packages, "./...")
 	}
-	cmdArgs := []string{"test"}
+	if changes, ok := FileChanges(ctx); ok && test.Affected {
+		packages = test.affected(ctx, printer, args.GoConfig.GoBin, packages, changes)
+		if len(packages) == 0 {
+			fmt.Fprintln(printer, "no affected packages")
+			// Replaces the report of an earlier iteration so it is not mistaken for this one
+			report := &TestReport{}
+			args.SetResult(TestReportKey, report)
+			err := test.writeJUnit(report)
+			printer.Done(err)
+			return err
+		}
+	}
+	cmdArgs := []string{"test", "-json"}
+	cmdArgs = append(cmdArgs, test.flags()...)
 	cmdArgs = append(cmdArgs, packages...)
 
-	err := runGoTool(ctx, printer, *args, cmdArgs)
+	// Not using runGoTool since its indentation would break the JSON
+	var output bytes.Buffer
+	gopher := *args
+	gopher.Stdout = &output
+	runner := &ExecCmdRunner{
+		Name: args.GoConfig.GoBin,
+		Args: cmdArgs,
+	}
+	err := runner.Run(ctx, &gopher)
+
+	report, parseErr := ParseTestReport(&output)
+	args.SetResult(TestReportKey, report)
+	if parseErr != nil {
+		printer.Warn(parseErr)
+	}
+	if summaryErr := report.WriteSummary(pretty.NewIndentedWriter(printer, "  ")); summaryErr != nil {
+		printer.Warn(summaryErr)
+	}
+	if junitErr := test.writeJUnit(report); junitErr != nil {
+		err = errors.Join(err, junitErr)
+	}
+
+	if _, failed, _ := report.Counts(); err != nil && failed > 0 {
+		err = fmt.Errorf("%d tests failed: %w", failed, err)
+	}
 	printer.Done(err)
 	return err
 }
 
+// Writes the report to [GoTest].JUnitReport if it is set.
+func (test *GoTest) writeJUnit(report *TestReport) error {
+	if test.JUnitReport == "" {
+		return nil
+	}
+	if err := report.WriteJUnitFile(test.JUnitReport); err != nil {
+		return fmt.Errorf("writing junit report: %w", err)
+	}
+	return nil
+}
+
+// Returns the packages affected by the changes, falling back to every package if it can not tell.
+func (test *GoTest) affected(ctx context.Context, printer *pretty.Printer, goBin string, packages []string, changes ChangeSet) []string {
+	list, err := listPackages(ctx, goBin, packages)
+	if err != nil {
+		printer.Warn(fmt.Errorf("listing packages, testing all: %w", err))
+		return packages
+	}
+	affected, all := affectedPackages(changes, list)
+	if all {
+		return packages
+	}
+	return affected
+}
+
 func (vet *GoVet) Run(ctx context.Context, args *Gopher) error {
 	printer := pretty.New(args.Stdout, "Go Vet")
 	printer.Start()
@@ -127,7 +334,7 @@ func (format *GoFormat) Run(ctx context.Context, args *Gopher) error {
 	if format.CheckOnly {
 		// TODO: This is a hack
 		// slog.Debug("running command", "cmd", args.GoConfig.GoBin, "args", cmdArgs)
-		cmd := exec.CommandContext(ctx, "gofmt", "-l", ".")
+		cmd := CommandContext(ctx, "gofmt", "-l", ".")
 		outputBytes, err := cmd.CombinedOutput()
 		output := string(outputBytes)
 		if len(strings.TrimSpace(output)) != 0 {
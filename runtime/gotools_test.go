@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoTestFlags(t *testing.T) {
+	test := GoTest{
+		Race:         true,
+		RunPattern:   "TestA/sub",
+		SkipPattern:  "TestB",
+		Count:        1,
+		Timeout:      2 * time.Minute,
+		Shuffle:      "on",
+		Short:        true,
+		Tags:         []string{"integration", "linux"},
+		CPU:          []int{1, 4},
+		FailFast:     true,
+		CoverProfile: "target/cover.out",
+		CoverMode:    "atomic",
+		CoverPkg:     []string{"./a/...", "./b/..."},
+		Flags:        []string{"-v"},
+	}
+	if err := test.Validate(); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	expected := []string{
+		"-race",
+		"-run", "TestA/sub",
+		"-skip", "TestB",
+		"-count", "1",
+		"-timeout", "2m0s",
+		"-shuffle", "on",
+		"-short",
+		"-tags", "integration,linux",
+		"-cpu", "1,4",
+		"-failfast",
+		"-coverprofile", "target/cover.out",
+		"-covermode", "atomic",
+		"-coverpkg", "./a/...,./b/...",
+		"-v",
+	}
+	if flags := test.flags(); !slices.Equal(flags, expected) {
+		t.Fatalf("got: %v expected: %v", flags, expected)
+	}
+	if flags := (&GoTest{}).flags(); len(flags) != 0 {
+		t.Fatalf("got: %v expected no flags", flags)
+	}
+}
+
+func TestGoTestValidate(t *testing.T) {
+	tests := []struct {
+		Test     GoTest
+		ErrorMsg string
+	}{
+		{Test: GoTest{Shuffle: "off"}},
+		{Test: GoTest{Shuffle: "1234"}},
+		{Test: GoTest{Count: -1}, ErrorMsg: "count"},
+		{Test: GoTest{Timeout: -time.Second}, ErrorMsg: "timeout"},
+		{Test: GoTest{Shuffle: "sometimes"}, ErrorMsg: "shuffle"},
+		{Test: GoTest{CoverMode: "all"}, ErrorMsg: "cover mode"},
+		{Test: GoTest{CPU: []int{0}}, ErrorMsg: "cpu"},
+		{Test: GoTest{RunPattern: "Test(/sub"}, ErrorMsg: "run pattern"},
+		{Test: GoTest{SkipPattern: "["}, ErrorMsg: "skip pattern"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ErrorMsg, func(t *testing.T) {
+			err := test.Test.Validate()
+			if test.ErrorMsg == "" && err != nil {
+				t.Fatalf("got error: %s", err.Error())
+			} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+				t.Fatalf(`got error: "%v" expected: "%s"`, err, test.ErrorMsg)
+			}
+		})
+	}
+}
+
+func TestGoTestNothingAffected(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a module")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":         "module example.com/affected\n\ngo 1.22\n",
+		"calc/calc.go":   "package calc\n",
+		"calc/a_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n",
+		"README.md":      "readme\n",
+	})
+	junit := filepath.Join(dir, "junit.xml")
+	test := GoTest{Affected: true, JUnitReport: junit}
+	var stdout strings.Builder
+	gopher := &Gopher{GoConfig: GoConfig{GoBin: "go"}, Stdout: &stdout}
+	if err := test.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+
+	// Only a file outside of any package changed
+	changes := ChangeSet{Changes: []FileChange{{Path: filepath.Join(dir, "README.md")}}}
+	if err := test.Run(withEventValue(context.Background(), changes), gopher); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	} else if !strings.Contains(stdout.String(), "no affected packages") {
+		t.Fatalf("got: %q expected no affected packages", stdout.String())
+	}
+	if report, ok := gopher.TestReport(); !ok || len(report.Packages) != 0 {
+		t.Fatalf("got: %v expected an empty report", report)
+	}
+	content, err := os.ReadFile(junit)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	var root junitTestSuites
+	if err := xml.Unmarshal(content, &root); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	} else if root.Tests != 0 {
+		t.Fatalf("got: %d tests expected: 0", root.Tests)
+	}
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+/*
+Reports whether the slash separated path relative to a watched root matches the glob.
+Globs use [path.Match] syntax. Globs without a slash match the base name at any depth,
+otherwise they match from the root and "**" matches any number of directories.
+*/
+func matchGlob(glob string, rel string) bool {
+	glob = strings.TrimSuffix(glob, "/")
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(rel))
+		return ok
+	}
+	return matchSegments(
+		strings.Split(strings.TrimPrefix(glob, "/"), "/"),
+		strings.Split(rel, "/"),
+	)
+}
+
+func matchSegments(globs []string, segments []string) bool {
+	for len(globs) > 0 {
+		if globs[0] == "**" {
+			globs = globs[1:]
+			if len(globs) == 0 {
+				return true
+			}
+			for i := range len(segments) + 1 {
+				if matchSegments(globs, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		} else if ok, _ := path.Match(globs[0], segments[0]); !ok {
+			return false
+		}
+		globs, segments = globs[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// A single pattern from a .gitignore file.
+type ignoreRule struct {
+	Base    string // Slash separated directory containing the .gitignore relative to the root. "." for the root.
+	Glob    string
+	Negate  bool // Pattern started with "!" and re-includes matches.
+	DirOnly bool // Pattern ended with "/" and only matches directories.
+}
+
+/*
+Parses the content of a .gitignore file found in base.
+Base is relative to the watched root and slash separated.
+*/
+func parseGitIgnore(content []byte, base string) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{Base: base}
+		if negated, ok := strings.CutPrefix(line, "!"); ok {
+			rule.Negate = true
+			line = negated
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if trimmed, ok := strings.CutSuffix(line, "/"); ok {
+			rule.DirOnly = true
+			line = trimmed
+		}
+		if strings.Contains(line, "/") && !strings.HasPrefix(line, "**/") {
+			// Patterns with a slash are anchored to the .gitignore's directory
+			line = "/" + strings.TrimPrefix(line, "/")
+		}
+		rule.Glob = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Reports whether the rule applies to the path relative to the watched root.
+func (rule ignoreRule) matches(rel string, isDir bool) bool {
+	if rule.DirOnly && !isDir {
+		return false
+	}
+	if rule.Base != "." {
+		sub, ok := strings.CutPrefix(rel, rule.Base+"/")
+		if !ok {
+			return false
+		}
+		rel = sub
+	}
+	return matchGlob(rule.Glob, rel)
+}
+
+/*
+Reports whether a path relative to the watched root is ignored by the rules.
+Like git, later rules take precedence and a file can not be re-included if one
+of its parent directories is ignored.
+*/
+func ignoredBy(rules []ignoreRule, rel string, isDir bool) bool {
+	segments := strings.Split(rel, "/")
+	for i := range segments {
+		prefix := strings.Join(segments[:i+1], "/")
+		prefixIsDir := isDir || i < len(segments)-1
+		ignored := false
+		for _, rule := range rules {
+			if rule.matches(prefix, prefixIsDir) {
+				ignored = !rule.Negate
+			}
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package runtime
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		Glob  string
+		Path  string
+		Match bool
+	}{
+		{Glob: "*.go", Path: "main.go", Match: true},
+		{Glob: "*.go", Path: "a/b/main.go", Match: true},
+		{Glob: "*.go", Path: "main.txt", Match: false},
+		{Glob: "target", Path: "a/target", Match: true},
+		{Glob: "/target", Path: "target", Match: true},
+		{Glob: "/target", Path: "a/target", Match: false},
+		{Glob: "a/*.go", Path: "a/main.go", Match: true},
+		{Glob: "a/*.go", Path: "a/b/main.go", Match: false},
+		{Glob: "a/**/*.go", Path: "a/main.go", Match: true},
+		{Glob: "a/**/*.go", Path: "a/b/c/main.go", Match: true},
+		{Glob: "**/testdata", Path: "a/b/testdata", Match: true},
+		{Glob: "a/**", Path: "a/b/c", Match: true},
+		{Glob: "a/**", Path: "b/a/c", Match: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %s", test.Glob, test.Path), func(t *testing.T) {
+			if match := matchGlob(test.Glob, test.Path); match != test.Match {
+				t.Fatalf("got: %t expected: %t", match, test.Match)
+			}
+		})
+	}
+}
+
+func TestGitIgnore(t *testing.T) {
+	content := []byte(`
+# Comment
+*.log
+!keep.log
+build/
+/vendor
+docs/*.html
+`)
+	rules := parseGitIgnore(content, ".")
+	rules = append(rules, parseGitIgnore([]byte("*.tmp\n"), "sub")...)
+
+	tests := []struct {
+		Path    string
+		IsDir   bool
+		Ignored bool
+	}{
+		{Path: "main.go", Ignored: false},
+		{Path: "debug.log", Ignored: true},
+		{Path: "a/debug.log", Ignored: true},
+		{Path: "keep.log", Ignored: false},
+		{Path: "build", IsDir: true, Ignored: true},
+		{Path: "build", IsDir: false, Ignored: false},
+		{Path: "build/main.go", Ignored: true},
+		{Path: "vendor", IsDir: true, Ignored: true},
+		{Path: "a/vendor", IsDir: true, Ignored: false},
+		{Path: "docs/index.html", Ignored: true},
+		{Path: "docs/a/index.html", Ignored: false},
+		{Path: "sub/a.tmp", Ignored: true},
+		{Path: "a.tmp", Ignored: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			if ignored := ignoredBy(rules, test.Path, test.IsDir); ignored != test.Ignored {
+				t.Fatalf("got: %t expected: %t", ignored, test.Ignored)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package runtime
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Root of a JUnit XML report. See https://github.com/testmoapp/junitxml for the format.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+/*
+Writes the report as JUnit XML with a testsuite per package and a testcase per test.
+Packages that failed without a failing test, such as build failures, get a testcase with an error.
+*/
+func (report *TestReport) WriteJUnit(stdout io.Writer) error {
+	root := junitTestSuites{}
+	var total time.Duration
+	for _, pkg := range report.Packages {
+		suite := junitTestSuite{
+			Name:      pkg.Name,
+			Time:      junitTime(pkg.Elapsed),
+			Timestamp: junitTimestamp(pkg.Started),
+		}
+		for _, test := range pkg.Tests {
+			testCase := junitTestCase{
+				Name:      test.Name,
+				ClassName: pkg.Name,
+				Time:      junitTime(test.Elapsed),
+			}
+			switch test.Status {
+			case TestFailed:
+				testCase.Failure = &junitMessage{Message: "Failed", Output: test.Output}
+				suite.Failures++
+			case TestRunning:
+				testCase.Error = &junitMessage{Message: "Did not finish", Output: test.Output}
+				suite.Errors++
+			case TestSkipped:
+				testCase.Skipped = &junitMessage{Message: "Skipped", Output: test.Output}
+				suite.Skipped++
+			default:
+				testCase.SystemOut = test.Output
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		if (pkg.Status == TestFailed || pkg.Status == TestRunning) && len(pkg.Failed()) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      pkg.Name,
+				ClassName: pkg.Name,
+				Time:      junitTime(pkg.Elapsed),
+				Error:     &junitMessage{Message: "Package failed", Output: pkg.Output},
+			})
+			suite.Errors++
+		} else {
+			suite.SystemOut = pkg.Output
+		}
+		suite.Tests = len(suite.Cases)
+
+		root.Suites = append(root.Suites, suite)
+		root.Tests += suite.Tests
+		root.Failures += suite.Failures
+		root.Errors += suite.Errors
+		root.Skipped += suite.Skipped
+		total += pkg.Elapsed
+	}
+	root.Time = junitTime(total)
+
+	if _, err := io.WriteString(stdout, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(stdout)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(root); err != nil {
+		return fmt.Errorf("encoding junit report: %w", err)
+	}
+	_, err := io.WriteString(stdout, "\n")
+	return err
+}
+
+// Writes the report as JUnit XML to the file, creating parent directories as needed.
+func (report *TestReport) WriteJUnitFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJUnit(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func junitTime(elapsed time.Duration) string {
+	return fmt.Sprintf("%.3f", elapsed.Seconds())
+}
+
+func junitTimestamp(started time.Time) string {
+	if started.IsZero() {
+		return ""
+	}
+	return started.UTC().Format("2006-01-02T15:04:05")
+}
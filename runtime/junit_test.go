@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestReportWriteJUnit(t *testing.T) {
+	report, err := ParseTestReport(strings.NewReader(testStream))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	path := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	if err := report.WriteJUnitFile(path); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	var root junitTestSuites
+	if err := xml.Unmarshal(content, &root); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if root.Tests != 6 || root.Failures != 2 || root.Errors != 1 || root.Skipped != 1 {
+		t.Fatalf("got: %d tests %d failures %d errors %d skipped expected: 6 2 1 1",
+			root.Tests, root.Failures, root.Errors, root.Skipped,
+		)
+	}
+	if len(root.Suites) != 4 {
+		t.Fatalf("got: %d suites expected: 4", len(root.Suites))
+	}
+
+	bad := root.Suites[0]
+	if bad.Name != "example.com/bad" || bad.Time != "1.250" {
+		t.Fatalf("got: %s %s expected: example.com/bad 1.250", bad.Name, bad.Time)
+	}
+	if sub := bad.Cases[2]; sub.Name != "TestFail/sub" || sub.Failure == nil || !strings.Contains(sub.Failure.Output, "boom") {
+		t.Fatalf("expected failure with output: %+v", sub)
+	}
+	if broken := root.Suites[1].Cases; len(broken) != 1 || broken[0].Error == nil ||
+		!strings.Contains(broken[0].Error.Output, "undefined: undefined") {
+		t.Fatalf("expected build failure as an error: %+v", broken)
+	}
+	if skip := root.Suites[3].Cases[1]; skip.Name != "TestSkip" || skip.Skipped == nil {
+		t.Fatalf("expected skipped test: %+v", skip)
+	}
+}
@@ -0,0 +1,26 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes files, keyed by their slash separated path, to a temporary directory and changes into it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+	}
+	t.Chdir(dir)
+	// Flags meant for this module do not apply to the temporary one
+	t.Setenv("GOFLAGS", "")
+	return dir
+}
@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	goruntime "runtime"
+)
+
+var _ Runner = &ParallelRunner{}
+var _ io.Closer = &ParallelRunner{}
+var _ Validator = &ParallelRunner{}
+
+/*
+[ParallelRunner] implements the [Runner] interface and runs its children concurrently.
+Each child writes to its own buffer which is flushed to [Gopher].Stdout in the order
+the children were given, so output is grouped instead of interleaved.
+You may use [Parallel] to initialize the struct with the default options.
+*/
+type ParallelRunner struct {
+	Runners   []Runner // Runners to call concurrently.
+	Workers   int      // Maximum number of runners running at once. If <= 0, defaults to the number of CPUs.
+	KeepGoing bool     // When false, the first failure cancels the remaining runners.
+}
+
+/*
+Shorthand syntax for creating a [ParallelRunner] that cancels on the first failure.
+*/
+func Parallel(runners ...Runner) Runner {
+	return &ParallelRunner{
+		Runners: runners,
+	}
+}
+
+func (parallel *ParallelRunner) Run(ctx context.Context, gopher *Gopher) error {
+	if err := parallel.Validate(); err != nil {
+		return err
+	}
+	workers := parallel.Workers
+	if workers <= 0 {
+		workers = goruntime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	type result struct {
+		output bytes.Buffer
+		err    error
+		done   chan struct{}
+	}
+	results := make([]*result, len(parallel.Runners))
+	semaphore := make(chan struct{}, workers)
+	for i, runner := range parallel.Runners {
+		results[i] = &result{done: make(chan struct{})}
+		go func(result *result) {
+			defer close(result.done)
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				result.err = context.Cause(ctx)
+				return
+			}
+			if err := ctx.Err(); err != nil {
+				result.err = context.Cause(ctx)
+				return
+			}
+
+			child := *gopher
+			child.Stdout = &result.output
+			result.err = runner.Run(ctx, &child)
+			if result.err != nil && !parallel.KeepGoing {
+				cancel(result.err)
+			}
+		}(results[i])
+	}
+
+	var errs []error
+	for _, result := range results {
+		<-result.done
+		if _, err := result.output.WriteTo(gopher.Stdout); err != nil {
+			errs = append(errs, err)
+		}
+		if result.err != nil {
+			errs = append(errs, result.err)
+		}
+	}
+
+	if !parallel.KeepGoing {
+		// Report the failure that caused the cancellation rather than every canceled sibling.
+		if cause := context.Cause(ctx); cause != nil && len(errs) > 0 {
+			return cause
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+Validates the children implementing [Validator]. A [Service] is rejected since it keeps
+writing output after its buffer was flushed at the end of the iteration.
+*/
+func (parallel *ParallelRunner) Validate() error {
+	errs := []error{validateRunners(parallel.Runners)}
+	for _, runner := range parallel.Runners {
+		if service, ok := runner.(*Service); ok {
+			errs = append(errs, fmt.Errorf("service %s cannot run in parallel", service.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closes the children implementing [io.Closer].
+func (parallel *ParallelRunner) Close() error {
+	var errs []error
+	for _, runner := range parallel.Runners {
+		if closer, ok := runner.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelGroupsOutput(t *testing.T) {
+	var output strings.Builder
+	gopher := Gopher{Stdout: &output}
+
+	var running, peak atomic.Int32
+	var runners []Runner
+	for i := range 4 {
+		runners = append(runners, RunnerFunc(func(ctx context.Context, gopher *Gopher) error {
+			now := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			// Later runners finish first to ensure output is still ordered
+			time.Sleep(time.Duration(4-i) * 10 * time.Millisecond)
+			fmt.Fprintf(gopher.Stdout, "start %d\n", i)
+			fmt.Fprintf(gopher.Stdout, "end %d\n", i)
+			return nil
+		}))
+	}
+
+	runner := &ParallelRunner{Runners: runners, Workers: 2}
+	if err := runner.Run(t.Context(), &gopher); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	expected := "start 0\nend 0\nstart 1\nend 1\nstart 2\nend 2\nstart 3\nend 3\n"
+	if output.String() != expected {
+		t.Fatalf(`got: "%s" expected: "%s"`, output.String(), expected)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("got %d concurrent runners expected at most: 2", peak.Load())
+	}
+}
+
+func TestParallelCancelsOnFailure(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		KeepGoing bool
+		Canceled  bool
+	}{
+		{KeepGoing: false, Canceled: true},
+		{KeepGoing: true, Canceled: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("keep going %t", test.KeepGoing), func(t *testing.T) {
+			var canceled atomic.Bool
+			started := make(chan struct{})
+			runner := &ParallelRunner{
+				Workers:   2,
+				KeepGoing: test.KeepGoing,
+				Runners: []Runner{
+					RunnerFunc(func(ctx context.Context, _ *Gopher) error {
+						<-started
+						return errFailed
+					}),
+					RunnerFunc(func(ctx context.Context, _ *Gopher) error {
+						close(started)
+						select {
+						case <-ctx.Done():
+							canceled.Store(true)
+							return ctx.Err()
+						case <-time.After(100 * time.Millisecond):
+							return nil
+						}
+					}),
+				},
+			}
+
+			err := runner.Run(t.Context(), &Gopher{Stdout: &strings.Builder{}})
+			if !errors.Is(err, errFailed) {
+				t.Fatalf("got error: %v expected: %v", err, errFailed)
+			} else if errors.Is(err, context.Canceled) {
+				t.Fatalf("got error: %v that includes canceled siblings", err)
+			} else if canceled.Load() != test.Canceled {
+				t.Fatalf("sibling canceled: %t expected: %t", canceled.Load(), test.Canceled)
+			}
+		})
+	}
+}
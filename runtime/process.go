@@ -0,0 +1,24 @@
+package runtime
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// Time a canceled command has to exit after SIGTERM before it is sent SIGKILL.
+const DefaultGracePeriod = 5 * time.Second
+
+/*
+Like [exec.CommandContext] but the command runs in its own process group.
+When ctx is done the whole group is sent SIGTERM then SIGKILL after [DefaultGracePeriod]
+if the command is still running, so grandchildren such as test binaries or servers started
+by `go run` do not outlive it. Use [ExecCmdRunner] to also kill members still running once
+the command exited.
+On platforms without process groups only the command itself is killed.
+*/
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	_ = setProcessGroup(cmd, DefaultGracePeriod)
+	return cmd
+}
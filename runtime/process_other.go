@@ -0,0 +1,14 @@
+//go:build !unix
+
+package runtime
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Process groups are not supported so only the command is killed, after the grace period for pipes to close.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) (release func()) {
+	cmd.WaitDelay = grace
+	return func() {}
+}
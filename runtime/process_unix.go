@@ -0,0 +1,55 @@
+//go:build unix
+
+package runtime
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+/*
+Starts the command in a new process group which is terminated as a whole when canceled.
+The returned function must be called once the command was waited on. It kills members of
+a canceled group that outlived the command without waiting out the rest of the grace period,
+see its comment for the window in which the group id may have been reused.
+*/
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) (release func()) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	var canceled atomic.Bool
+	cmd.Cancel = func() error {
+		// The group shares the id of its leader, negative ids signal the whole group
+		pgid := -cmd.Process.Pid
+		err := syscall.Kill(pgid, syscall.SIGTERM)
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		canceled.Store(true)
+		// Members may ignore SIGTERM. Once the leader is reaped its id may be reused
+		// by an unrelated group, so the group is only killed while the leader is unreaped.
+		time.AfterFunc(grace, func() {
+			if !errors.Is(cmd.Process.Signal(syscall.Signal(0)), os.ErrProcessDone) {
+				_ = syscall.Kill(pgid, syscall.SIGKILL)
+			}
+		})
+		return err
+	}
+	// Kills the leader and stops waiting on pipes held open by the group if it is still running
+	cmd.WaitDelay = grace
+	return func() {
+		if canceled.Load() {
+			// The leader is reaped by now, so the group id is only held by members that outlived it.
+			// If they all exited too the id could have been reused by an unrelated group before
+			// this signal. Where pids are allocated in sequence, such as on Linux, that requires them
+			// to wrap around in between. The window is accepted since members ignoring SIGTERM
+			// would otherwise keep running.
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}
+}
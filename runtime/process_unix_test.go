@@ -0,0 +1,84 @@
+//go:build unix
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Waits for the process to exit, returning false if it is still running after the timeout.
+func waitForExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			return true
+		}
+		// Reaped by init once its parent is gone, until then it is a zombie
+		if stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat")); err == nil && strings.Contains(string(stat), ") Z ") {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestExecCmdRunnerKillsProcessGroup(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Script string
+	}{
+		// The grandchild keeps running after the shell is gone
+		{Name: "grandchild", Script: "sleep 30 & echo $! > %s; wait"},
+		// SIGTERM is ignored so SIGKILL is needed after the grace period
+		{Name: "ignores SIGTERM", Script: "trap '' TERM; sh -c \"trap '' TERM; sleep 30\" & echo $! > %s; wait"},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			pidFile := filepath.Join(t.TempDir(), "pid")
+			runner := ExecCmdRunner{
+				Name:        "sh",
+				Args:        []string{"-c", strings.ReplaceAll(test.Script, "%s", pidFile)},
+				GracePeriod: 200 * time.Millisecond,
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- runner.Run(ctx, &Gopher{Stdout: &strings.Builder{}})
+			}()
+
+			var pid int
+			for range 200 {
+				content, _ := os.ReadFile(pidFile)
+				if pid, _ = strconv.Atoi(strings.TrimSpace(string(content))); pid != 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if pid == 0 {
+				t.Fatalf("grandchild never started")
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Fatalf("expected an error after canceling")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("command did not stop after being canceled")
+			}
+			if !waitForExit(pid, 2*time.Second) {
+				syscall.Kill(pid, syscall.SIGKILL)
+				t.Fatalf("grandchild %d is still running", pid)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+// Defaults of [WaitReady].
+const (
+	DefaultReadyTimeout     = 30 * time.Second
+	DefaultReadyInterval    = 50 * time.Millisecond // Wait after the first failed attempt.
+	DefaultReadyMaxInterval = 1 * time.Second       // Longest wait between attempts.
+)
+
+/*
+Probes check once whether something, such as a [Service], is ready.
+They return nil when ready and an error describing why not otherwise.
+Probes may implement [Validator] to have their options checked before waiting.
+See [WaitReady].
+*/
+type Probe interface {
+	Probe(context.Context) error
+}
+
+// Wrapped by errors of a [Probe] that can not succeed by retrying, such as when the process exited.
+var ErrNeverReady = errors.New("will never be ready")
+
+var _ Runner = &WaitReady{}
+var _ Validator = &WaitReady{}
+var _ Validator = &LogProbe{}
+
+/*
+[WaitReady] implements the [Runner] interface and retries a [Probe] until it succeeds.
+The wait between attempts starts at Interval and doubles up to MaxInterval.
+Errors wrapping [ErrNeverReady] stop the wait early.
+Place it after a [Service] so later runners, such as integration tests, only run once the service is ready.
+Ex: &WaitReady{Probe: &HTTPProbe{URL: "http://localhost:8080/health"}}
+*/
+type WaitReady struct {
+	Probe       Probe
+	Timeout     time.Duration // If 0, defaults to [DefaultReadyTimeout].
+	Interval    time.Duration // If 0, defaults to [DefaultReadyInterval].
+	MaxInterval time.Duration // If 0, defaults to [DefaultReadyMaxInterval].
+}
+
+// Returns an error if there is no probe or the probe's own validation fails.
+func (wait *WaitReady) Validate() error {
+	if wait.Probe == nil {
+		return errors.New("no probe to wait on")
+	}
+	if validator, ok := wait.Probe.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+func (wait *WaitReady) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, fmt.Sprintf("Ready %v", wait.Probe))
+	printer.Start()
+	if err := wait.Validate(); err != nil {
+		printer.Done(err)
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	err := wait.wait(ctx)
+	printer.Done(err)
+	return err
+}
+
+func (wait *WaitReady) wait(ctx context.Context) error {
+	timeout := cmp.Or(wait.Timeout, DefaultReadyTimeout)
+	interval := cmp.Or(wait.Interval, DefaultReadyInterval)
+	maxInterval := cmp.Or(wait.MaxInterval, DefaultReadyMaxInterval)
+
+	// Attempts share the deadline so a hanging probe cannot outlive the timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	for {
+		err := wait.Probe.Probe(ctx)
+		if err == nil || errors.Is(err, ErrNeverReady) {
+			return err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("not ready after %s: %w", time.Since(start).Round(time.Millisecond), err)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(2*interval, maxInterval)
+	}
+}
+
+// [Probe] that is ready once Address accepts TCP connections. Ex: localhost:8080
+type TCPProbe struct {
+	Address string
+}
+
+func (probe *TCPProbe) String() string {
+	return "tcp " + probe.Address
+}
+
+func (probe *TCPProbe) Probe(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", probe.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// [Probe] that is ready once a GET request to URL returns a 2xx status.
+type HTTPProbe struct {
+	URL    string
+	Client *http.Client // If nil, defaults to [http.DefaultClient].
+}
+
+func (probe *HTTPProbe) String() string {
+	return probe.URL
+}
+
+func (probe *HTTPProbe) Probe(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.URL, nil)
+	if err != nil {
+		return err
+	}
+	client := probe.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", probe.URL, response.Status)
+	}
+	return nil
+}
+
+/*
+[Probe] that is ready once a line of the [Service]'s output matches Pattern.
+Only output of the process started by the current iteration is matched.
+It fails without retrying once the process is no longer running.
+Ex: &LogProbe{Service: server, Pattern: regexp.MustCompile("listening on")}
+*/
+type LogProbe struct {
+	Service *Service
+	Pattern *regexp.Regexp
+}
+
+func (probe *LogProbe) String() string {
+	if probe.Service == nil {
+		return fmt.Sprintf("log /%s/", probe.Pattern)
+	}
+	return fmt.Sprintf("log %s /%s/", probe.Service.Name, probe.Pattern)
+}
+
+// Returns an error if the service or pattern is missing.
+func (probe *LogProbe) Validate() error {
+	var errs []error
+	if probe.Service == nil {
+		errs = append(errs, errors.New("log probe has no service"))
+	}
+	if probe.Pattern == nil {
+		errs = append(errs, errors.New("log probe has no pattern"))
+	}
+	return errors.Join(errs...)
+}
+
+func (probe *LogProbe) Probe(ctx context.Context) error {
+	lines, ok := probe.Service.Logs()
+	if !ok {
+		// Nothing starts the service again while waiting
+		return fmt.Errorf("%s is not running: %w", probe.Service.Name, ErrNeverReady)
+	}
+	for _, line := range lines {
+		if probe.Pattern.MatchString(line) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no output matching /%s/", probe.Pattern)
+}
+
+// [Probe] that is ready once Path exists. Ex: a socket or pid file.
+type FileProbe struct {
+	Path string
+}
+
+func (probe *FileProbe) String() string {
+	return "file " + probe.Path
+}
+
+func (probe *FileProbe) Probe(ctx context.Context) error {
+	_, err := os.Stat(probe.Path)
+	return err
+}
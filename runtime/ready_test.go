@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitReady(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %s", err)
+	}
+	defer listener.Close()
+	// Bound then closed so nothing accepts connections on it
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %s", err)
+	}
+	closed.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	// Created while waiting to exercise retrying
+	dir := t.TempDir()
+	late := filepath.Join(dir, "late")
+	time.AfterFunc(100*time.Millisecond, func() {
+		os.WriteFile(late, nil, 0o644)
+	})
+
+	tests := []struct {
+		Probe    Probe
+		ErrorMsg string
+	}{
+		{Probe: &TCPProbe{Address: listener.Addr().String()}},
+		{Probe: &TCPProbe{Address: closed.Addr().String()}, ErrorMsg: "not ready after"},
+		{Probe: &HTTPProbe{URL: healthy.URL}},
+		{Probe: &HTTPProbe{URL: unhealthy.URL}, ErrorMsg: "503 Service Unavailable"},
+		{Probe: &FileProbe{Path: late}},
+		{Probe: &FileProbe{Path: filepath.Join(dir, "missing")}, ErrorMsg: "no such file or directory"},
+	}
+	for _, test := range tests {
+		wait := WaitReady{Probe: test.Probe, Timeout: 500 * time.Millisecond}
+		err := wait.Run(context.Background(), &Gopher{Stdout: &strings.Builder{}})
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("%v: got error: %s", test.Probe, err.Error())
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+			t.Fatalf("%v: got: %v expected error containing: %s", test.Probe, err, test.ErrorMsg)
+		}
+	}
+}
+
+func TestLogProbe(t *testing.T) {
+	service := &Service{
+		Name:        "sh",
+		Args:        []string{"-c", "echo starting; sleep 0.1; echo listening on :8080; exec sleep 30"},
+		GracePeriod: 200 * time.Millisecond,
+	}
+	defer service.Close()
+	gopher := &Gopher{Stdout: &notifyWriter{writes: make(chan string, 100)}}
+
+	probe := &LogProbe{Service: service, Pattern: regexp.MustCompile(`listening on :\d+`)}
+	if err := probe.Probe(context.Background()); !errors.Is(err, ErrNeverReady) {
+		t.Fatalf("got: %v expected: %v", err, ErrNeverReady)
+	}
+	err := gopher.RunNow(context.Background(), service, &WaitReady{Probe: probe, Timeout: 3 * time.Second})
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+}
+
+func TestLogProbeExited(t *testing.T) {
+	service := &Service{Name: "sh", Args: []string{"-c", "echo crashed; exit 1"}}
+	defer service.Close()
+	gopher := &Gopher{Stdout: &strings.Builder{}}
+
+	start := time.Now()
+	wait := &WaitReady{Probe: &LogProbe{Service: service, Pattern: regexp.MustCompile("listening")}, Timeout: 10 * time.Second}
+	if err := gopher.RunNow(context.Background(), service, wait); !errors.Is(err, ErrNeverReady) {
+		t.Fatalf("got: %v expected: %v", err, ErrNeverReady)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("took %s to fail", elapsed)
+	}
+}
+
+func TestWaitReadyValidate(t *testing.T) {
+	tests := []struct {
+		Wait     WaitReady
+		ErrorMsg string
+	}{
+		{Wait: WaitReady{Probe: &FileProbe{Path: "file"}}},
+		{Wait: WaitReady{}, ErrorMsg: "no probe"},
+		{Wait: WaitReady{Probe: &LogProbe{Pattern: regexp.MustCompile("ready")}}, ErrorMsg: "no service"},
+		{Wait: WaitReady{Probe: &LogProbe{Service: &Service{Name: "sh"}}}, ErrorMsg: "no pattern"},
+	}
+	for _, test := range tests {
+		err := test.Wait.Validate()
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("%v: got error: %s", test.Wait.Probe, err.Error())
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+			t.Fatalf("%v: got: %v expected error containing: %s", test.Wait.Probe, err, test.ErrorMsg)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package runtime
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ohhfishal/gopher/cache"
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+var _ Runner = &ReleaseArchives{}
+
+// Archive name template of [ReleaseArchives] when Name is empty.
+const DefaultReleaseName = "{{.Name}}_{{.Version}}_{{.OS}}_{{.Arch}}{{with .Variant}}_{{.}}{{end}}"
+
+/*
+[ReleaseArchives] implements the [Runner] interface and packages binaries into archives
+along with a SHA256SUMS file and a JSON manifest of the archives.
+It is meant to run after a [GoBuild] with Platforms set.
+*/
+type ReleaseArchives struct {
+	Artifacts []Artifact // Binaries to package. If empty, defaults to [Gopher.Artifacts].
+	Files     []string   // Additional files added to every archive. Ex: README.md or LICENSE
+	Output    string     // Directory the archives are written to. If empty, defaults to target/release.
+	// [text/template] of the archive names without extension, defaulting to [DefaultReleaseName].
+	// It is executed with the Name, Version, OS, Arch and Variant of each binary.
+	Name string
+	// Either "tar.gz" or "zip". If empty, windows binaries use zip and everything else tar.gz.
+	Format string
+}
+
+// Manifest [ReleaseArchives] writes to manifest.json.
+type ReleaseManifest struct {
+	Version   string            `json:"version"`
+	Commit    string            `json:"commit"`
+	Date      time.Time         `json:"date"`
+	Artifacts []ReleaseArtifact `json:"artifacts"`
+}
+
+// An archive produced by [ReleaseArchives].
+type ReleaseArtifact struct {
+	Name    string `json:"name"` // File name of the archive.
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Variant string `json:"variant,omitempty"` // GOARM or GOAMD64 if set.
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+// A file added to an archive.
+type archiveFile struct {
+	Source string
+	Name   string // Name inside of the archive.
+	Mode   os.FileMode
+}
+
+func (release *ReleaseArchives) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Release Archives")
+	printer.Start()
+	err := release.run(ctx, printer, args)
+	printer.Done(err)
+	return err
+}
+
+func (release *ReleaseArchives) run(ctx context.Context, printer *pretty.Printer, args *Gopher) error {
+	artifacts := release.Artifacts
+	if len(artifacts) == 0 {
+		artifacts, _ = args.Artifacts()
+	}
+	if len(artifacts) == 0 {
+		return errors.New("no artifacts to release, run a GoBuild with Platforms first")
+	}
+	if !slices.Contains([]string{"", "tar.gz", "zip"}, release.Format) {
+		return fmt.Errorf(`format must be "tar.gz" or "zip": %q`, release.Format)
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(cmp.Or(release.Name, DefaultReleaseName))
+	if err != nil {
+		return fmt.Errorf("parsing name template: %w", err)
+	}
+	output := cmp.Or(release.Output, filepath.Join("target", "release"))
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return err
+	}
+
+	info := GitVersion(ctx, "")
+	manifest := ReleaseManifest{
+		Version: info.Version,
+		Commit:  info.Commit,
+		Date:    info.Date,
+	}
+	var archives []Artifact
+	for _, artifact := range artifacts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var name strings.Builder
+		if err := tmpl.Execute(&name, map[string]string{
+			"Name":    artifact.Name,
+			"Version": info.Version,
+			"OS":      artifact.Platform.OS,
+			"Arch":    artifact.Platform.Arch,
+			"Variant": artifact.Platform.Variant(),
+		}); err != nil {
+			return fmt.Errorf("executing name template: %w", err)
+		}
+
+		binary := artifact.Name
+		if artifact.Platform.OS == "windows" {
+			binary += ".exe"
+		}
+		files := []archiveFile{{Source: artifact.Path, Name: binary, Mode: 0755}}
+		for _, file := range release.Files {
+			files = append(files, archiveFile{Source: file, Name: filepath.Base(file), Mode: 0644})
+		}
+
+		format := release.Format
+		if format == "" && artifact.Platform.OS == "windows" {
+			format = "zip"
+		} else if format == "" {
+			format = "tar.gz"
+		}
+		path := filepath.Join(output, name.String()+"."+format)
+		if err := writeArchive(path, format, files, info.Date); err != nil {
+			return fmt.Errorf("archiving %s: %w", artifact.Path, err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		manifest.Artifacts = append(manifest.Artifacts, ReleaseArtifact{
+			Name:    filepath.Base(path),
+			OS:      artifact.Platform.OS,
+			Arch:    artifact.Platform.Arch,
+			Variant: artifact.Platform.Variant(),
+			Size:    int64(len(content)),
+			SHA256:  cache.Hash(content),
+		})
+		archives = append(archives, Artifact{Name: artifact.Name, Platform: artifact.Platform, Path: path, Size: int64(len(content))})
+	}
+
+	if err := writeChecksums(filepath.Join(output, "SHA256SUMS"), manifest.Artifacts); err != nil {
+		return fmt.Errorf("writing checksums: %w", err)
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(output, "manifest.json"), append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	writeArtifactTable(pretty.NewIndentedWriter(printer, "  "), archives)
+	return nil
+}
+
+// Writes SHA256SUMS in the format read by sha256sum -c.
+func writeChecksums(path string, artifacts []ReleaseArtifact) error {
+	var builder strings.Builder
+	for _, artifact := range artifacts {
+		fmt.Fprintf(&builder, "%s  %s\n", artifact.SHA256, artifact.Name)
+	}
+	return os.WriteFile(path, []byte(builder.String()), 0644)
+}
+
+// Writes the files into a tar.gz or zip archive with a fixed modification time so builds are reproducible.
+func writeArchive(path string, format string, files []archiveFile, modified time.Time) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if format == "zip" {
+		err = writeZip(output, files, modified)
+	} else {
+		err = writeTarGz(output, files, modified)
+	}
+	return errors.Join(err, output.Close())
+}
+
+func writeTarGz(output io.Writer, files []archiveFile, modified time.Time) error {
+	compressed := gzip.NewWriter(output)
+	archive := tar.NewWriter(compressed)
+	for _, file := range files {
+		content, err := os.ReadFile(file.Source)
+		if err != nil {
+			return err
+		}
+		if err := archive.WriteHeader(&tar.Header{
+			Name:    file.Name,
+			Mode:    int64(file.Mode),
+			Size:    int64(len(content)),
+			ModTime: modified,
+			Format:  tar.FormatPAX,
+		}); err != nil {
+			return err
+		}
+		if _, err := archive.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func writeZip(output io.Writer, files []archiveFile, modified time.Time) error {
+	archive := zip.NewWriter(output)
+	for _, file := range files {
+		content, err := os.ReadFile(file.Source)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		header.SetMode(file.Mode)
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ohhfishal/gopher/cache"
+)
+
+func TestReleaseArchives(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"tool_linux_arm64":       "linux binary",
+		"tool_windows_amd64.exe": "windows binary",
+		"README.md":              "readme",
+	})
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	gopher := &Gopher{Stdout: &strings.Builder{}}
+	if err := (&ReleaseArchives{}).Run(context.Background(), gopher); err == nil || !strings.Contains(err.Error(), "no artifacts") {
+		t.Fatalf("got: %v expected no artifacts", err)
+	}
+
+	gopher.SetResult(ArtifactsKey, []Artifact{
+		{Name: "tool", Platform: Platform{OS: "linux", Arch: "arm64"}, Path: "tool_linux_arm64"},
+		{Name: "tool", Platform: Platform{OS: "windows", Arch: "amd64"}, Path: "tool_windows_amd64.exe"},
+	})
+	release := ReleaseArchives{Files: []string{"README.md"}}
+	if err := release.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	output := filepath.Join(dir, "target", "release")
+	tarball := filepath.Join(output, "tool_"+DevelVersion+"_linux_arm64.tar.gz")
+	if names := tarNames(t, tarball); !slices.Equal(names, []string{"tool", "README.md"}) {
+		t.Fatalf("got: %v expected: [tool README.md]", names)
+	}
+	zipped := filepath.Join(output, "tool_"+DevelVersion+"_windows_amd64.zip")
+	reader, err := zip.OpenReader(zipped)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	defer reader.Close()
+	if len(reader.File) != 2 || reader.File[0].Name != "tool.exe" || reader.File[0].Mode() != 0755 {
+		t.Fatalf("expected an executable tool.exe: %v", reader.File)
+	}
+
+	var manifest ReleaseManifest
+	content, err := os.ReadFile(filepath.Join(output, "manifest.json"))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if len(manifest.Artifacts) != 2 || manifest.Version != DevelVersion || manifest.Date.Unix() != 1700000000 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	sums, err := os.ReadFile(filepath.Join(output, "SHA256SUMS"))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	for _, artifact := range manifest.Artifacts {
+		hash, err := cache.HashFile(filepath.Join(output, artifact.Name))
+		if err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+		if artifact.SHA256 != hash || !strings.Contains(string(sums), hash+"  "+artifact.Name+"\n") {
+			t.Fatalf("checksum of %s does not match: %s", artifact.Name, sums)
+		}
+	}
+
+	// Archives are reproducible
+	if err := release.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	again, err := os.ReadFile(filepath.Join(output, "SHA256SUMS"))
+	if err != nil || string(again) != string(sums) {
+		t.Fatalf("got: %s expected: %s", again, sums)
+	}
+}
+
+func tarNames(t *testing.T, path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	var names []string
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		} else if err != nil {
+			t.Fatalf("got error: %s", err.Error())
+		}
+		names = append(names, header.Name)
+	}
+}
@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"sync"
+)
+
+/*
+Values runners store for later runners in the same [Gopher.Run] iteration.
+Safe for concurrent use so runners inside of [Parallel] may share it.
+*/
+type results struct {
+	lock   sync.Mutex
+	values map[string]any
+}
+
+// Stores a value for later runners under key, replacing any previous value.
+func (gopher *Gopher) SetResult(key string, value any) {
+	if gopher.results == nil {
+		gopher.results = &results{}
+	}
+	gopher.results.lock.Lock()
+	defer gopher.results.lock.Unlock()
+	if gopher.results.values == nil {
+		gopher.results.values = map[string]any{}
+	}
+	gopher.results.values[key] = value
+}
+
+// Returns the value stored under key by an earlier runner.
+func (gopher *Gopher) Result(key string) (any, bool) {
+	if gopher.results == nil {
+		return nil, false
+	}
+	gopher.results.lock.Lock()
+	defer gopher.results.lock.Unlock()
+	value, ok := gopher.results.values[key]
+	return value, ok
+}
+
+// Returns the value stored under key if it is of type T.
+func ResultAs[T any](gopher *Gopher, key string) (T, bool) {
+	value, _ := gopher.Result(key)
+	typed, ok := value.(T)
+	return typed, ok
+}
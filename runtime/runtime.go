@@ -6,9 +6,11 @@
 //
 // # Available Runners
 //
-// Standard Go Tooling: [GoTest], [GoVet], [GoBuild] [GoFormat]
+// Standard Go Tooling: [GoTest], [GoVet], [GoBuild] [GoFormat], [GoCoverage], [GoBench], [GoFuzz]
 //
-// Quality of life: [ExecCmdRunner], [Status.Done], [Status.Start]
+// Releases: [ReleaseArchives]
+//
+// Quality of life: [ExecCmdRunner], [Shell], [Service], [WaitReady], [Parallel], [Status.Done], [Status.Start]
 package runtime
 
 import (
@@ -17,10 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
+
+	"github.com/ohhfishal/gopher/pretty"
 )
 
-// TODO: Hook interfaces? Let runners define an Init, Run and Close methods
+// TODO: Hook interfaces? Let runners define an Init method
 
 // Sentinel error to notify the caller to stop break the run loop until the next [Event].
 var ErrSkip = errors.New("stop and skip iteration")
@@ -32,11 +35,22 @@ type GoConfig struct {
 /*
 Runners wrap a method to be called in a [Gopher.Run] event loop.
 Ex: go build or go fmt
+Runners that also implement [io.Closer] are closed once the loop ends, such as [Service].
+Runners that also implement [Validator] are validated before the loop starts, such as [GoTest].
 */
 type Runner interface {
 	Run(context.Context, *Gopher) error
 }
 
+/*
+Validator is implemented by runners whose options can be checked without running them.
+[Gopher.RunWith] validates its runners once before the first iteration, so invalid options
+fail immediately instead of failing every iteration of a watch loop.
+*/
+type Validator interface {
+	Validate() error
+}
+
 type runner struct {
 	f func(context.Context, *Gopher) error
 }
@@ -61,24 +75,178 @@ type Gopher struct {
 	GoConfig GoConfig
 	Stdout   io.Writer
 	Target   string
+	results  *results // Values shared between runners. See [Gopher.SetResult].
+}
+
+/*
+Options for [Gopher.RunWith].
+*/
+type RunOptions struct {
+	// When true, a new event cancels the in-flight iteration and starts a fresh one.
+	// Events that arrive while the iteration is being canceled are coalesced into one.
+	CancelOnEvent bool
+	// Called with the result of every iteration that runs to completion, nil on success.
+	// When set, failures are no longer printed to [Gopher].Stdout.
+	OnResult func(error)
 }
 
 /*
 Calls all runners sequentially when event triggers.
 The next runner is only called if the previous returns nil.
-You may return [ErrSkip] to not have error output written to Gopher.Stdout.
+You may return [ErrSkip] to stop the iteration without it counting as a failure.
+
+Once event stops yielding, the error of the last iteration is returned.
+For [Now] that is the error of its single iteration, so a failure can fail the target.
+Failures in unbounded events such as [OnFileChange] do not stop the loop. Each failure
+is printed to [Gopher].Stdout right after its iteration, or use [RunOptions].OnResult to
+observe them instead. Use [AlreadyPrinted] to avoid printing the returned error again.
+If ctx is canceled, Run returns nil once the in-flight iteration finishes, without waiting for the next event.
 */
 func (gopher *Gopher) Run(ctx context.Context, event Event, runners ...Runner) error {
-	for range event {
-		if ctx.Err() != nil {
+	return gopher.RunWith(ctx, RunOptions{}, event, runners...)
+}
+
+/*
+Same as [Gopher.Run] but configured using [RunOptions].
+*/
+func (gopher *Gopher) RunWith(ctx context.Context, options RunOptions, event Event, runners ...Runner) error {
+	if err := validateRunners(runners); err != nil {
+		return fmt.Errorf("invalid runners: %w", err)
+	}
+	defer closeRunners(gopher.Stdout, runners)
+	if options.CancelOnEvent {
+		return gopher.runCancelOnEvent(ctx, options, event, runners...)
+	}
+	// Waiting on the event in the background lets a canceled ctx end the loop without another event
+	events := make(chan any)
+	go func() {
+		defer close(events)
+		for value := range event {
+			select {
+			case events <- value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var last error // Result of the latest iteration, returned if the event stops yielding
+	for {
+		select {
+		case <-ctx.Done():
 			return nil
+		case value, ok := <-events:
+			if !ok {
+				return last
+			} else if ctx.Err() != nil {
+				return nil
+			}
+			runCtx, cancel := context.WithCancel(withEventValue(ctx, value))
+			last = gopher.report(options, gopher.run(runCtx, runners...))
+			cancel()
+		}
+	}
+}
+
+func (gopher *Gopher) runCancelOnEvent(ctx context.Context, options RunOptions, event Event, runners ...Runner) error {
+	// Buffer of 1 so events that arrive mid-iteration are coalesced rather than queued.
+	events := make(chan any, 1)
+	go func() {
+		defer close(events)
+		for value := range event {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case events <- value:
+			case pending := <-events:
+				events <- mergeEventValues(pending, value)
+			}
+		}
+	}()
+
+	var last error // Result of the latest completed iteration, returned if the event stops yielding
+	cancel := context.CancelFunc(func() {})
+	defer func() { cancel() }()
+	var done chan error
+	var current any // Value of the in-flight iteration's event
+	for {
+		select {
+		case <-ctx.Done():
+			if done != nil {
+				<-done
+			}
+			return nil
+		case err := <-done:
+			done = nil
+			last = gopher.report(options, err)
+			if events == nil {
+				return last
+			}
+		case value, ok := <-events:
+			if !ok {
+				// The event finished so let the in-flight iteration complete
+				events = nil
+				if done == nil {
+					return last
+				}
+				continue
+			}
+			cancel()
+			if done != nil {
+				// Canceled iterations are expected to fail so their result is dropped
+				// and their event is carried over into the next iteration.
+				<-done
+				value = mergeEventValues(current, value)
+			}
+			// Coalesce events that arrived while canceling since this iteration covers them
+			select {
+			case latest, ok := <-events:
+				if ok {
+					value = mergeEventValues(value, latest)
+				} else {
+					events = nil
+				}
+			default:
+			}
+
+			last = nil
+			current = value
+			cancel, done = gopher.start(withEventValue(ctx, value), runners...)
+		}
+	}
+}
+
+// Runs an iteration in the background. The returned channel receives its result.
+func (gopher *Gopher) start(ctx context.Context, runners ...Runner) (context.CancelFunc, chan error) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- gopher.run(ctx, runners...)
+	}()
+	return cancel, done
+}
+
+// Returns the errors of the runners implementing [Validator].
+func validateRunners(runners []Runner) error {
+	var errs []error
+	for _, runner := range runners {
+		if validator, ok := runner.(Validator); ok {
+			errs = append(errs, validator.Validate())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Closes the runners implementing [io.Closer], printing any errors since the loop has already ended.
+func closeRunners(stdout io.Writer, runners []Runner) {
+	for _, runner := range runners {
+		if closer, ok := runner.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				pretty.Fwarnf(stdout, "closing runner: %s\n", err)
+			}
 		}
-		runCtx, cancel := context.WithCancel(ctx)
-		gopher.run(runCtx, runners...)
-		// TODO: This may need to be canceled *at the start* of the next iteration
-		cancel()
 	}
-	return nil
 }
 
 /*
@@ -88,14 +256,53 @@ func (gopher *Gopher) RunNow(ctx context.Context, runners ...Runner) error {
 	return gopher.Run(ctx, Now(), runners...)
 }
 
-func (gopher *Gopher) run(ctx context.Context, runners ...Runner) {
+func (gopher *Gopher) run(ctx context.Context, runners ...Runner) error {
+	// Each iteration starts fresh, the last iteration's results stay available after Run returns
+	gopher.results = &results{}
 	for _, runner := range runners {
 		err := runner.Run(ctx, gopher)
-		if errors.Is(ErrSkip, err) {
-			return
+		if errors.Is(err, ErrSkip) {
+			return nil
 		} else if err != nil {
-			fmt.Fprintln(os.Stdout, err)
-			return
+			return err
 		}
 	}
+	return nil
+}
+
+/*
+Reports the result of an iteration right after it finished, before the next one can clear the screen.
+Returns the error marked as printed if it was.
+*/
+func (gopher *Gopher) report(options RunOptions, err error) error {
+	if options.OnResult != nil {
+		options.OnResult(err)
+		return err
+	} else if err == nil {
+		return nil
+	}
+	fmt.Fprintln(gopher.Stdout, err)
+	return &printedError{err: err}
+}
+
+// An error [Gopher.Run] already printed to [Gopher].Stdout.
+type printedError struct {
+	err error
+}
+
+func (err *printedError) Error() string {
+	return err.err.Error()
+}
+
+func (err *printedError) Unwrap() error {
+	return err.err
+}
+
+/*
+Reports whether err, or an error it wraps, was already printed by [Gopher.Run].
+Callers printing the errors of their targets can use it to avoid printing the same error twice.
+*/
+func AlreadyPrinted(err error) bool {
+	var printed *printedError
+	return errors.As(err, &printed)
 }
@@ -2,7 +2,11 @@ package runtime
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
 )
 
 func ExampleGopher_Run() {
@@ -46,3 +50,130 @@ func ExampleGopher_RunNow() {
 		panic(err)
 	}
 }
+
+func TestRunCancelOnEvent(t *testing.T) {
+	trigger := make(chan struct{})
+	event := Event(func(yield func(any) bool) {
+		for range trigger {
+			if !yield(nil) {
+				return
+			}
+		}
+	})
+
+	var iterations, canceled atomic.Int32
+	started := make(chan struct{})
+	blocking := RunnerFunc(func(ctx context.Context, _ *Gopher) error {
+		iteration := iterations.Add(1)
+		started <- struct{}{}
+		if iteration > 1 {
+			return nil
+		}
+		<-ctx.Done()
+		canceled.Add(1)
+		return ctx.Err()
+	})
+
+	gopher := Gopher{Stdout: &strings.Builder{}}
+	errs := make(chan error)
+	go func() {
+		errs <- gopher.RunWith(t.Context(), RunOptions{CancelOnEvent: true}, event, blocking)
+	}()
+
+	trigger <- struct{}{}
+	<-started
+	// The second event cancels the first iteration that would otherwise never finish
+	trigger <- struct{}{}
+	<-started
+	close(trigger)
+
+	if err := <-errs; err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	} else if iterations.Load() != 2 {
+		t.Fatalf("got %d iterations expected: 2", iterations.Load())
+	} else if canceled.Load() != 1 {
+		t.Fatalf("got %d canceled iterations expected: 1", canceled.Load())
+	}
+}
+
+func TestRunReturnsErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	failing := RunnerFunc(func(context.Context, *Gopher) error {
+		return errFailed
+	})
+	skipping := RunnerFunc(func(context.Context, *Gopher) error {
+		return ErrSkip
+	})
+
+	var output strings.Builder
+	gopher := Gopher{Stdout: &output}
+	// Printed right away and marked so the caller does not print it again
+	if err := gopher.RunNow(t.Context(), failing); !errors.Is(err, errFailed) {
+		t.Fatalf("got error: %v expected: %v", err, errFailed)
+	} else if !AlreadyPrinted(err) {
+		t.Fatalf("got error: %v expected it to be marked as printed", err)
+	} else if output.String() != errFailed.Error()+"\n" {
+		t.Fatalf(`got output: "%s" expected the error to be printed once`, output.String())
+	}
+
+	// Each iteration's failure is printed as soon as it finishes
+	output.Reset()
+	var printed []string
+	printing := RunnerFunc(func(context.Context, *Gopher) error {
+		printed = append(printed, output.String())
+		return errFailed
+	})
+	if err := gopher.Run(t.Context(), NowAnd(Now()), printing); !errors.Is(err, errFailed) {
+		t.Fatalf("got error: %v expected: %v", err, errFailed)
+	} else if len(printed) != 2 || printed[1] != errFailed.Error()+"\n" {
+		t.Fatalf(`got output before each iteration: %q expected the first failure before the second`, printed)
+	}
+	var calls int
+	recovering := RunnerFunc(func(context.Context, *Gopher) error {
+		if calls++; calls == 1 {
+			return errFailed
+		}
+		return nil
+	})
+	if err := gopher.Run(t.Context(), NowAnd(Now()), recovering); err != nil {
+		t.Fatalf("got error: %v expected the last iteration's result: nil", err)
+	}
+
+	if err := gopher.RunNow(t.Context(), skipping, failing); err != nil {
+		t.Fatalf("got error: %v expected: nil", err)
+	}
+
+	var results []error
+	options := RunOptions{OnResult: func(err error) {
+		results = append(results, err)
+	}}
+	output.Reset()
+	event := NowAnd(Now())
+	if err := gopher.RunWith(t.Context(), options, event, failing); !errors.Is(err, errFailed) {
+		t.Fatalf("got error: %v expected: %v", err, errFailed)
+	} else if len(results) != 2 || !errors.Is(results[1], errFailed) {
+		t.Fatalf("got results: %v expected 2 failures", results)
+	} else if output.Len() != 0 {
+		t.Fatalf(`got output: "%s" expected OnResult to replace printing`, output.String())
+	}
+}
+
+func TestRunValidatesBeforeLoop(t *testing.T) {
+	var iterations atomic.Int32
+	counting := RunnerFunc(func(context.Context, *Gopher) error {
+		iterations.Add(1)
+		return nil
+	})
+	// Never yields so only validating up front can fail the loop
+	never := Event(func(yield func(any) bool) {
+		<-t.Context().Done()
+	})
+
+	gopher := Gopher{Stdout: &strings.Builder{}}
+	err := gopher.Run(t.Context(), never, counting, Parallel(&GoTest{Count: -1}))
+	if err == nil || !strings.Contains(err.Error(), "count must not be negative") {
+		t.Fatalf("got: %v expected an invalid count error", err)
+	} else if iterations.Load() != 0 {
+		t.Fatalf("got %d iterations expected: 0", iterations.Load())
+	}
+}
@@ -0,0 +1,187 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ohhfishal/gopher/pretty"
+)
+
+var _ Runner = &Service{}
+var _ io.Closer = &Service{}
+
+/*
+[Service] implements the [Runner] interface and runs a long-lived process, such as a dev server,
+in the background. Each time an iteration reaches it the running process is stopped and started
+again, so placing it after a [GoBuild] restarts the server after every successful build.
+The process is stopped once [Gopher.Run] returns, so it should be used with an event that
+keeps yielding such as [OnFileChange].
+Stopping sends SIGTERM to the process group and SIGKILL after the grace period.
+It cannot run inside [Parallel] since its output outlives the iteration's buffers.
+*/
+type Service struct {
+	Name        string        // Same as [exec.CommandContext]
+	Args        []string      // Same as [exec.CommandContext]
+	Dir         string        // Same as [exec.CommandContext]
+	Env         []string      // Added to the environment of the current process.
+	Prefix      string        // Added to every line of output. If empty, defaults to "[name] ".
+	GracePeriod time.Duration // Time to exit after SIGTERM before SIGKILL. If 0, defaults to [DefaultGracePeriod].
+
+	lock    sync.Mutex
+	process *serviceProcess
+}
+
+// Number of output lines kept for [Service.Logs].
+const serviceLogLines = 1000
+
+type serviceProcess struct {
+	cancel  context.CancelFunc
+	done    chan struct{} // Closed once the process exited.
+	stopped atomic.Bool   // Whether the exit was requested by stop.
+
+	lock  sync.Mutex
+	lines []string // Last lines of output.
+}
+
+// Records complete lines written by the process.
+func (process *serviceProcess) Write(content []byte) (int, error) {
+	process.lock.Lock()
+	defer process.lock.Unlock()
+	for line := range strings.Lines(string(content)) {
+		process.lines = append(process.lines, strings.TrimSuffix(line, "\n"))
+	}
+	if extra := len(process.lines) - serviceLogLines; extra > 0 {
+		process.lines = append(process.lines[:0], process.lines[extra:]...)
+	}
+	return len(content), nil
+}
+
+func (service *Service) Run(ctx context.Context, args *Gopher) error {
+	printer := pretty.New(args.Stdout, "Service "+service.Name)
+	printer.Start()
+	service.lock.Lock()
+	defer service.lock.Unlock()
+
+	if service.process != nil {
+		fmt.Fprintln(printer, "restarting")
+		service.stop()
+	}
+	err := service.start(ctx, args.Stdout)
+	printer.Done(err)
+	return err
+}
+
+/*
+Returns the last lines of output of the running process, or false if it is not running.
+Lines of processes started by previous iterations are not included.
+*/
+func (service *Service) Logs() ([]string, bool) {
+	service.lock.Lock()
+	process := service.process
+	service.lock.Unlock()
+	if process == nil {
+		return nil, false
+	}
+	select {
+	case <-process.done:
+		return nil, false
+	default:
+	}
+	process.lock.Lock()
+	defer process.lock.Unlock()
+	return slices.Clone(process.lines), true
+}
+
+// Stops the process if it is running.
+func (service *Service) Close() error {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	service.stop()
+	return nil
+}
+
+func (service *Service) start(ctx context.Context, stdout io.Writer) error {
+	// The process outlives the iteration that started it
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	cmd := exec.CommandContext(ctx, service.Name, service.Args...)
+	release := setProcessGroup(cmd, cmp.Or(service.GracePeriod, DefaultGracePeriod))
+	cmd.Dir = service.Dir
+	if len(service.Env) > 0 {
+		cmd.Env = append(os.Environ(), service.Env...)
+	}
+	prefix := service.Prefix
+	if prefix == "" {
+		prefix = "[" + filepath.Base(service.Name) + "] "
+	}
+	process := &serviceProcess{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	output := &lineWriter{
+		stdout: io.MultiWriter(&prefixWriter{stdout: stdout, prefix: prefix}, process),
+		lock:   &sync.Mutex{},
+	}
+	cmd.Stdout, cmd.Stderr = output, output
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+	service.process = process
+	go func() {
+		defer close(process.done)
+		err := cmd.Wait()
+		release()
+		output.Flush()
+		if process.stopped.Load() {
+			return
+		}
+		// Crashed or exited on its own, the next iteration starts it again
+		if err != nil {
+			pretty.Fwarnf(stdout, "%s exited: %s\n", service.Name, err)
+		} else {
+			pretty.Fwarnf(stdout, "%s exited\n", service.Name)
+		}
+	}()
+	return nil
+}
+
+// Stops the process and waits for it to exit. Must be called with the lock held.
+func (service *Service) stop() {
+	process := service.process
+	if process == nil {
+		return
+	}
+	service.process = nil
+	process.stopped.Store(true)
+	process.cancel()
+	<-process.done
+}
+
+// Writer that prefixes every line, writing each call at once so output of concurrent services is not mixed.
+type prefixWriter struct {
+	stdout io.Writer
+	prefix string
+}
+
+func (writer *prefixWriter) Write(content []byte) (int, error) {
+	var builder strings.Builder
+	for line := range strings.Lines(string(content)) {
+		builder.WriteString(writer.prefix)
+		builder.WriteString(line)
+	}
+	if _, err := io.WriteString(writer.stdout, builder.String()); err != nil {
+		return 0, err
+	}
+	return len(content), nil
+}
@@ -0,0 +1,108 @@
+//go:build unix
+
+package runtime
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Waits for the service to print its pid.
+func waitForPid(t *testing.T, writer *notifyWriter) int {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case line := <-writer.writes:
+			if pid, ok := strings.CutPrefix(line, "[sh] pid "); ok {
+				value, err := strconv.Atoi(strings.TrimSpace(pid))
+				if err != nil {
+					t.Fatalf("parsing pid: %s", err)
+				}
+				return value
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the service to start")
+		}
+	}
+}
+
+func TestServiceRestarts(t *testing.T) {
+	writer := &notifyWriter{writes: make(chan string, 100)}
+	service := &Service{
+		Name:        "sh",
+		Args:        []string{"-c", "echo pid $$; exec sleep 30"},
+		GracePeriod: 200 * time.Millisecond,
+	}
+	gopher := &Gopher{Stdout: writer}
+
+	if err := service.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("starting: %s", err)
+	}
+	first := waitForPid(t, writer)
+
+	if err := service.Run(context.Background(), gopher); err != nil {
+		t.Fatalf("restarting: %s", err)
+	}
+	if !waitForExit(first, 3*time.Second) {
+		t.Fatalf("process %d still running after restart", first)
+	}
+	second := waitForPid(t, writer)
+	if first == second {
+		t.Fatalf("got: %d expected a new process", second)
+	}
+
+	if err := service.Close(); err != nil {
+		t.Fatalf("closing: %s", err)
+	}
+	if !waitForExit(second, 3*time.Second) {
+		t.Fatalf("process %d still running after close", second)
+	}
+}
+
+func TestRunClosesService(t *testing.T) {
+	writer := &notifyWriter{writes: make(chan string, 100)}
+	service := &Service{
+		Name:        "sh",
+		Args:        []string{"-c", "echo pid $$; exec sleep 30"},
+		GracePeriod: 200 * time.Millisecond,
+	}
+	gopher := &Gopher{Stdout: writer}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		// The event keeps yielding so only canceling ctx ends the loop
+		done <- gopher.Run(ctx, NowAnd(AfterEvery(time.Hour)), service)
+	}()
+	pid := waitForPid(t, writer)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("got: %v expected: nil", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+	if !waitForExit(pid, 3*time.Second) {
+		t.Fatalf("process %d still running after Run returned", pid)
+	}
+}
+
+func TestParallelRejectsService(t *testing.T) {
+	service := &Service{Name: "sh", Args: []string{"-c", "echo pid $$; exec sleep 30"}}
+	defer service.Close()
+	gopher := &Gopher{Stdout: &strings.Builder{}}
+
+	err := Parallel(ExecCommand("true"), Parallel(service)).Run(context.Background(), gopher)
+	if err == nil || !strings.Contains(err.Error(), "service sh cannot run in parallel") {
+		t.Fatalf("got: %v expected the service to be rejected", err)
+	} else if _, running := service.Logs(); running {
+		t.Fatalf("service started inside Parallel")
+	}
+}
@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+var _ Runner = &Shell{}
+
+/*
+[Shell] implements the [Runner] interface and runs a script using an embedded POSIX shell
+interpreter instead of /bin/sh, so it behaves the same on every platform.
+Pipes, redirects, &&, variable expansion and globbing are supported.
+Programs the script runs are started like [ExecCmdRunner] so canceling terminates their process groups.
+You may use [ShellCommand] to initialize the struct with only a script.
+Ex: &Shell{Script: "go list ./... | grep -v /internal/ > target/packages.txt"}
+*/
+type Shell struct {
+	Script  string
+	Dir     string   // Directory the script starts in. If empty, defaults to the current directory.
+	Env     []string // Added to the environment of the current process. Ex: GOOS=linux
+	Stdin   io.Reader
+	Stdout  io.Writer     // Where stdout is written. If nil, defaults to [Gopher].Stdout.
+	Stderr  io.Writer     // Where stderr is written. If nil, defaults to the same writer as stdout.
+	Timeout time.Duration // If > 0, the script is stopped after running for the duration.
+	// Time programs have to exit after SIGTERM before they are sent SIGKILL. If 0, defaults to [DefaultGracePeriod].
+	GracePeriod time.Duration
+	HideOutput  bool // When true, does not print script output to [Gopher].Stdout
+}
+
+/*
+Shorthand syntax for creating a [Shell] that only runs script.
+*/
+func ShellCommand(script string) Runner {
+	return &Shell{Script: script}
+}
+
+func (shell *Shell) String() string {
+	return shell.Script
+}
+
+func (shell *Shell) Run(ctx context.Context, args *Gopher) error {
+	file, err := syntax.NewParser().Parse(strings.NewReader(shell.Script), "")
+	if err != nil {
+		return fmt.Errorf("parsing script: %w", err)
+	}
+	if shell.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, shell.Timeout, errRunnerTimeout)
+		defer cancel()
+	}
+
+	stdout := shell.Stdout
+	if stdout == nil {
+		stdout = args.Stdout
+	}
+	if shell.HideOutput {
+		stdout = io.Discard
+	}
+	outLines, errLines := lineWriters(stdout, shell.Stderr)
+	runner, err := interp.New(
+		interp.StdIO(shell.Stdin, outLines, errLines),
+		interp.Dir(shell.Dir),
+		// Later values take precedence
+		interp.Env(expand.ListEnviron(append(os.Environ(), shell.Env...)...)),
+		interp.ExecHandlers(shellExecHandler(cmp.Or(shell.GracePeriod, DefaultGracePeriod))),
+	)
+	if err != nil {
+		return err
+	}
+	err = runner.Run(ctx, file)
+	outLines.Flush()
+	errLines.Flush()
+	if err == nil {
+		return nil
+	}
+
+	exitErr := &ExitError{Command: shell.String(), Code: -1, Err: err}
+	var status interp.ExitStatus
+	if errors.Is(context.Cause(ctx), errRunnerTimeout) {
+		exitErr.TimedOut = true
+		exitErr.Err = fmt.Errorf("after %s: %w", shell.Timeout, context.DeadlineExceeded)
+	} else if errors.As(err, &status) {
+		exitErr.Code = int(status)
+	}
+	return exitErr
+}
+
+// Runs programs in their own process group, matching the interpreter's default handler otherwise.
+func shellExecHandler(grace time.Duration) func(interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(_ interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			handler := interp.HandlerCtx(ctx)
+			path, err := interp.LookPathDir(handler.Dir, handler.Env, args[0])
+			if err != nil {
+				fmt.Fprintln(handler.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+			cmd := exec.CommandContext(ctx, path, args[1:]...)
+			cmd.Args[0] = args[0]
+			release := setProcessGroup(cmd, grace)
+			cmd.Dir = handler.Dir
+			cmd.Env = shellEnviron(handler.Env)
+			if file, ok := handler.Stdin.(*os.File); !ok || file != nil {
+				cmd.Stdin = handler.Stdin
+			}
+			cmd.Stdout, cmd.Stderr = handler.Stdout, handler.Stderr
+
+			err = cmd.Run()
+			release()
+			var processErr *exec.ExitError
+			switch {
+			case err == nil:
+				return nil
+			case ctx.Err() != nil:
+				return ctx.Err()
+			case errors.As(err, &processErr):
+				// Such as SIGPIPE when the next command in a pipeline exits early
+				if status, ok := processErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+					return interp.ExitStatus(128 + int(status.Signal()))
+				}
+				return interp.ExitStatus(processErr.ExitCode())
+			}
+			fmt.Fprintln(handler.Stderr, err)
+			return interp.ExitStatus(127)
+		}
+	}
+}
+
+// Returns the exported variables of env to pass to a program.
+func shellEnviron(env expand.Environ) []string {
+	var environ []string
+	env.Each(func(name string, variable expand.Variable) bool {
+		if variable.Exported && variable.IsSet() && variable.Kind == expand.String {
+			environ = append(environ, name+"="+variable.String())
+		}
+		return true
+	})
+	return environ
+}
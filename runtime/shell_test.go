@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShell(t *testing.T) {
+	t.Setenv("GOPHER_INHERITED", "inherited")
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0o644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	tests := []struct {
+		Shell    Shell
+		Output   string
+		ErrorMsg string
+	}{
+		{
+			Shell:  Shell{Script: "echo $GOPHER_INHERITED ${GOPHER_ADDED}", Env: []string{"GOPHER_ADDED=added"}},
+			Output: "inherited added\n",
+		},
+		{
+			Shell:  Shell{Script: "echo *.go", Dir: dir},
+			Output: "a.go b.go\n",
+		},
+		{
+			Shell:  Shell{Script: "cat *.go | grep b | tr a-z A-Z", Dir: dir},
+			Output: "B.GO\n",
+		},
+		{
+			Shell:  Shell{Script: "echo first > out.txt && echo second >> out.txt && cat out.txt", Dir: dir},
+			Output: "first\nsecond\n",
+		},
+		{
+			// yes is killed by SIGPIPE once head exits
+			Shell:  Shell{Script: "yes | head -n 1"},
+			Output: "y\n",
+		},
+		{
+			Shell:  Shell{Script: "false && echo skipped || echo fallback"},
+			Output: "fallback\n",
+		},
+		{
+			Shell:  Shell{Script: "cat", Stdin: strings.NewReader("from stdin")},
+			Output: "from stdin",
+		},
+		{
+			// Exported variables reach programs, others do not
+			Shell:  Shell{Script: "export EXPORTED=yes; LOCAL=no; sh -c 'echo $EXPORTED $LOCAL'"},
+			Output: "yes\n",
+		},
+		{
+			Shell:  Shell{Script: "echo out; echo err >&2", Stderr: &strings.Builder{}},
+			Output: "out\n",
+		},
+		{
+			Shell:  Shell{Script: "echo hidden", HideOutput: true},
+			Output: "",
+		},
+		{
+			Shell:    Shell{Script: "echo failing; exit 3"},
+			Output:   "failing\n",
+			ErrorMsg: "echo failing; exit 3: exit status 3",
+		},
+		{
+			Shell:    Shell{Script: "gopher-command-that-does-not-exist"},
+			Output:   "\"gopher-command-that-does-not-exist\": executable file not found in $PATH\n",
+			ErrorMsg: "exit status 127",
+		},
+		{
+			Shell:    Shell{Script: "sleep 10", Timeout: 50 * time.Millisecond},
+			ErrorMsg: "sleep 10: timed out: after 50ms: context deadline exceeded",
+		},
+		{
+			Shell:    Shell{Script: "echo 'unterminated"},
+			ErrorMsg: "parsing script:",
+		},
+	}
+	for _, test := range tests {
+		var stdout strings.Builder
+		err := test.Shell.Run(context.Background(), &Gopher{Stdout: &stdout})
+		if test.ErrorMsg == "" && err != nil {
+			t.Fatalf("%s: got error: %s", test.Shell.Script, err.Error())
+		} else if test.ErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), test.ErrorMsg)) {
+			t.Fatalf("%s: got: %v expected error containing: %s", test.Shell.Script, err, test.ErrorMsg)
+		}
+		if stdout.String() != test.Output {
+			t.Fatalf("%s: got: %q expected: %q", test.Shell.Script, stdout.String(), test.Output)
+		}
+	}
+}
+
+func TestShellCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := ShellCommand("sleep 10 | cat").Run(ctx, &Gopher{Stdout: &strings.Builder{}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got: %v expected: %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("took %s to cancel", elapsed)
+	}
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// A cell of a table written by [writeTable].
+type tableCell struct {
+	Text  string
+	Color *color.Color // If nil, the cell is not coloured.
+}
+
+/*
+Writes rows as columns padded to their widest cell. The first labels columns are aligned left,
+the rest are values aligned right.
+*/
+func writeTable(stdout io.Writer, rows [][]tableCell, labels int) {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], len(cell.Text))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			text := fmt.Sprintf("%*s", widths[i], cell.Text)
+			if i < labels {
+				text = fmt.Sprintf("%-*s", widths[i], cell.Text)
+			}
+			// Padding before colouring since the escape codes would throw off the widths
+			if cell.Color != nil {
+				text = cell.Color.Sprint(text)
+			}
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(text)
+		}
+		fmt.Fprintln(stdout, strings.TrimRight(line.String(), " "))
+	}
+}
@@ -0,0 +1,240 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Key [GoTest] stores its [TestReport] under. See [Gopher.TestReport].
+const TestReportKey = "go-test"
+
+// Status of a test or package. Matches the actions reported by `go test -json`.
+type TestStatus string
+
+const (
+	TestRunning TestStatus = "run" // Never finished, such as when the test binary crashed.
+	TestPassed  TestStatus = "pass"
+	TestFailed  TestStatus = "fail"
+	TestSkipped TestStatus = "skip"
+)
+
+// An event emitted by `go test -json`. See `go doc test2json`.
+type TestEvent struct {
+	Time        time.Time
+	Action      string
+	Package     string
+	Test        string
+	Elapsed     float64 // Seconds
+	Output      string
+	ImportPath  string // Set for build-output and build-fail events.
+	FailedBuild string // Import path of the package that failed to build.
+}
+
+// Result of a single test or subtest.
+type TestResult struct {
+	Name    string // Full name including parent tests. Ex: TestA/sub
+	Status  TestStatus
+	Elapsed time.Duration
+	Output  string
+}
+
+// Results of a package's tests in the order they started.
+type PackageResult struct {
+	Name    string // Import path of the package.
+	Status  TestStatus
+	Started time.Time
+	Elapsed time.Duration
+	Output  string // Output not belonging to a test, such as build errors or coverage.
+	Tests   []*TestResult
+}
+
+// Returns the tests that failed.
+func (pkg *PackageResult) Failed() []*TestResult {
+	var failed []*TestResult
+	for _, test := range pkg.Tests {
+		if test.Status == TestFailed {
+			failed = append(failed, test)
+		}
+	}
+	return failed
+}
+
+// Results of a `go test -json` run.
+type TestReport struct {
+	Packages []*PackageResult // In the order they were first seen.
+	Output   string           // Lines that were not JSON events, such as errors printed by the go command.
+}
+
+// Returns the [TestReport] stored by the last [GoTest] that ran in this iteration.
+func (gopher *Gopher) TestReport() (*TestReport, bool) {
+	return ResultAs[*TestReport](gopher, TestReportKey)
+}
+
+/*
+Parses the output of `go test -json` into a [TestReport].
+Lines that are not JSON events are kept in [TestReport].Output.
+*/
+func ParseTestReport(reader io.Reader) (*TestReport, error) {
+	report := &TestReport{}
+	packages := map[string]*PackageResult{}
+	tests := map[string]*TestResult{}
+	builds := map[string]*strings.Builder{}
+
+	pkgResult := func(name string) *PackageResult {
+		pkg, ok := packages[name]
+		if !ok {
+			pkg = &PackageResult{Name: name, Status: TestRunning}
+			packages[name] = pkg
+			report.Packages = append(report.Packages, pkg)
+		}
+		return pkg
+	}
+
+	var raw strings.Builder
+	scanner := bufio.NewScanner(reader)
+	// Test output lines can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var event TestEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &event) != nil {
+			raw.Write(line)
+			raw.WriteByte('\n')
+			continue
+		}
+
+		switch event.Action {
+		case "build-output":
+			if builds[event.ImportPath] == nil {
+				builds[event.ImportPath] = &strings.Builder{}
+			}
+			builds[event.ImportPath].WriteString(event.Output)
+			continue
+		case "build-fail":
+			continue
+		}
+		if event.Package == "" {
+			continue
+		}
+
+		pkg := pkgResult(event.Package)
+		if pkg.Started.IsZero() {
+			pkg.Started = event.Time
+		}
+		if event.Test == "" {
+			switch event.Action {
+			case "output":
+				pkg.Output += event.Output
+			case "pass", "fail", "skip":
+				pkg.Status = TestStatus(event.Action)
+				pkg.Elapsed = seconds(event.Elapsed)
+				if build, ok := builds[event.FailedBuild]; ok {
+					pkg.Output = build.String() + pkg.Output
+				}
+			}
+			continue
+		}
+
+		key := event.Package + " " + event.Test
+		test, ok := tests[key]
+		if !ok {
+			test = &TestResult{Name: event.Test, Status: TestRunning}
+			tests[key] = test
+			pkg.Tests = append(pkg.Tests, test)
+		}
+		switch event.Action {
+		case "output":
+			test.Output += event.Output
+		case "pass", "fail", "skip":
+			test.Status = TestStatus(event.Action)
+			test.Elapsed = seconds(event.Elapsed)
+		}
+	}
+	report.Output = raw.String()
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("reading test output: %w", err)
+	}
+	return report, nil
+}
+
+func seconds(elapsed float64) time.Duration {
+	return time.Duration(elapsed * float64(time.Second))
+}
+
+// Reports whether any package or test failed or never finished.
+func (report *TestReport) Failed() bool {
+	for _, pkg := range report.Packages {
+		if pkg.Status == TestFailed || pkg.Status == TestRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the number of tests that passed, failed and were skipped.
+func (report *TestReport) Counts() (passed int, failed int, skipped int) {
+	for _, pkg := range report.Packages {
+		for _, test := range pkg.Tests {
+			switch test.Status {
+			case TestPassed:
+				passed++
+			case TestFailed, TestRunning:
+				failed++
+			case TestSkipped:
+				skipped++
+			}
+		}
+	}
+	return passed, failed, skipped
+}
+
+/*
+Writes a concise summary of the report.
+Only the output of failed tests and packages is written, followed by the totals.
+*/
+func (report *TestReport) WriteSummary(stdout io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString(report.Output)
+	for _, pkg := range report.Packages {
+		if pkg.Status != TestFailed && pkg.Status != TestRunning {
+			continue
+		}
+		fmt.Fprintf(&builder, "FAIL %s (%s)\n", pkg.Name, pkg.Elapsed)
+		failed := pkg.Failed()
+		for _, test := range failed {
+			writeTestOutput(&builder, test.Output)
+		}
+		if len(failed) == 0 {
+			// Build failures, panics outside of tests and timeouts only show in the package output
+			writeTestOutput(&builder, pkg.Output)
+		}
+	}
+
+	passed, failed, skipped := report.Counts()
+	fmt.Fprintf(&builder, "%d passed, %d failed, %d skipped in %d packages\n",
+		passed,
+		failed,
+		skipped,
+		len(report.Packages),
+	)
+	_, err := io.WriteString(stdout, builder.String())
+	return err
+}
+
+// Writes output indented, dropping the noise go test adds around each test.
+func writeTestOutput(stdout io.Writer, output string) {
+	for line := range strings.Lines(output) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "FAIL" || trimmed == "PASS" ||
+			strings.HasPrefix(trimmed, "=== ") ||
+			strings.HasPrefix(trimmed, "FAIL\t") ||
+			strings.HasPrefix(trimmed, "ok  \t") {
+			continue
+		}
+		io.WriteString(stdout, "  "+strings.TrimSuffix(line, "\n")+"\n")
+	}
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Trimmed output of `go test -json ./...` on a module with passing, failing, skipped and broken packages
+const testStream = `{"Action":"start","Package":"example.com/bad"}
+{"Action":"run","Package":"example.com/bad","Test":"TestPass"}
+{"Action":"output","Package":"example.com/bad","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Action":"pass","Package":"example.com/bad","Test":"TestPass","Elapsed":0.5}
+{"Action":"run","Package":"example.com/bad","Test":"TestFail"}
+{"Action":"run","Package":"example.com/bad","Test":"TestFail/sub"}
+{"Action":"output","Package":"example.com/bad","Test":"TestFail/sub","Output":"    bad_test.go:7: boom\n"}
+{"Action":"output","Package":"example.com/bad","Test":"TestFail/sub","Output":"--- FAIL: TestFail/sub (0.00s)\n"}
+{"Action":"fail","Package":"example.com/bad","Test":"TestFail/sub","Elapsed":0}
+{"Action":"fail","Package":"example.com/bad","Test":"TestFail","Elapsed":0}
+{"Action":"output","Package":"example.com/bad","Output":"FAIL\texample.com/bad\t0.004s\n"}
+{"Action":"fail","Package":"example.com/bad","Elapsed":1.25}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"# example.com/broken [example.com/broken.test]\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"broken_test.go:5:33: undefined: undefined\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Action":"start","Package":"example.com/broken"}
+{"Action":"output","Package":"example.com/broken","Output":"FAIL\texample.com/broken [build failed]\n"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+{"Action":"start","Package":"example.com/empty"}
+{"Action":"skip","Package":"example.com/empty","Elapsed":0}
+go: downloading example.com/dep v1.0.0
+{"Action":"start","Package":"example.com/ok"}
+{"Action":"run","Package":"example.com/ok","Test":"TestPass"}
+{"Action":"output","Package":"example.com/ok","Test":"TestPass","Output":"    ok_test.go:5: hidden\n"}
+{"Action":"pass","Package":"example.com/ok","Test":"TestPass","Elapsed":0}
+{"Action":"run","Package":"example.com/ok","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/ok","Test":"TestSkip","Elapsed":0}
+{"Action":"pass","Package":"example.com/ok","Elapsed":0.004}
+`
+
+func TestParseTestReport(t *testing.T) {
+	report, err := ParseTestReport(strings.NewReader(testStream))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	statuses := map[string]TestStatus{}
+	for _, pkg := range report.Packages {
+		statuses[pkg.Name] = pkg.Status
+		for _, test := range pkg.Tests {
+			statuses[pkg.Name+" "+test.Name] = test.Status
+		}
+	}
+	expected := map[string]TestStatus{
+		"example.com/bad":              TestFailed,
+		"example.com/bad TestPass":     TestPassed,
+		"example.com/bad TestFail":     TestFailed,
+		"example.com/bad TestFail/sub": TestFailed,
+		"example.com/broken":           TestFailed,
+		"example.com/empty":            TestSkipped,
+		"example.com/ok":               TestPassed,
+		"example.com/ok TestPass":      TestPassed,
+		"example.com/ok TestSkip":      TestSkipped,
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("got: %v expected: %v", statuses, expected)
+	}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Fatalf("%s: got: %q expected: %q", name, statuses[name], status)
+		}
+	}
+
+	if !report.Failed() {
+		t.Fatalf("expected report to have failed")
+	}
+	if passed, failed, skipped := report.Counts(); passed != 2 || failed != 2 || skipped != 1 {
+		t.Fatalf("got: %d passed %d failed %d skipped expected: 2 2 1", passed, failed, skipped)
+	}
+	if elapsed := report.Packages[0].Elapsed; elapsed != 1250*time.Millisecond {
+		t.Fatalf("got: %s expected: 1.25s", elapsed)
+	}
+	if broken := report.Packages[1].Output; !strings.Contains(broken, "undefined: undefined") {
+		t.Fatalf("expected build output in package output: %q", broken)
+	}
+	if report.Output != "go: downloading example.com/dep v1.0.0\n" {
+		t.Fatalf("got: %q expected non JSON lines", report.Output)
+	}
+}
+
+func TestTestReportWriteSummary(t *testing.T) {
+	report, err := ParseTestReport(strings.NewReader(testStream))
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+
+	var output strings.Builder
+	if err := report.WriteSummary(&output); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	expected := `go: downloading example.com/dep v1.0.0
+FAIL example.com/bad (1.25s)
+      bad_test.go:7: boom
+  --- FAIL: TestFail/sub (0.00s)
+FAIL example.com/broken (0s)
+  # example.com/broken [example.com/broken.test]
+  broken_test.go:5:33: undefined: undefined
+2 passed, 2 failed, 1 skipped in 4 packages
+`
+	if output.String() != expected {
+		t.Fatalf("got: %q expected: %q", output.String(), expected)
+	}
+	if strings.Contains(output.String(), "hidden") {
+		t.Fatalf("output of passing tests should not be shown")
+	}
+}
@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version reported by [GitVersion] outside of a git repository or before the first commit.
+const DevelVersion = "(devel)"
+
+/*
+Variables [GoBuild] stamps using -ldflags -X with values from [GitVersion].
+Each field is the fully qualified name of a string variable, empty fields are not stamped.
+Ex: main.version or github.com/org/tool/internal/build.Version
+*/
+type Stamp struct {
+	Version string // Set to the output of git describe --tags --always --dirty.
+	Commit  string // Set to the full commit hash.
+	Dirty   string // Set to "true" or "false" depending on uncommitted changes.
+	Date    string // Set to the build time formatted using [time.RFC3339].
+}
+
+// Reports whether any variable should be stamped.
+func (stamp Stamp) enabled() bool {
+	return stamp != Stamp{}
+}
+
+// Version information of the working tree.
+type VersionInfo struct {
+	Version string    // Ex: v1.2.0, v1.2.0-3-g1a2b3c4-dirty or 1a2b3c4 without tags.
+	Commit  string    // Full commit hash or "unknown".
+	Dirty   bool      // Whether the working tree has uncommitted changes.
+	Date    time.Time // Build time. Uses $SOURCE_DATE_EPOCH if set for reproducible builds.
+}
+
+/*
+Returns the version information of the git repository in dir, or the current directory if empty.
+Outside of a git repository, Version is [DevelVersion] and Commit is "unknown".
+*/
+func GitVersion(ctx context.Context, dir string) VersionInfo {
+	info := VersionInfo{
+		Version: DevelVersion,
+		Commit:  "unknown",
+		Date:    time.Now().UTC(),
+	}
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		info.Date = time.Unix(epoch, 0).UTC()
+	}
+
+	commit, dirty, err := gitCommit(ctx, dir)
+	if err != nil {
+		return info
+	}
+	info.Commit, info.Dirty = commit, dirty
+
+	cmd := CommandContext(ctx, "git", "describe", "--tags", "--always", "--dirty")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		info.Version = strings.TrimSpace(string(output))
+	}
+	return info
+}
+
+// Returns the -X flags setting the variables of the stamp.
+func (stamp Stamp) ldflags(info VersionInfo) string {
+	var flags []string
+	for _, variable := range []struct{ name, value string }{
+		{stamp.Version, info.Version},
+		{stamp.Commit, info.Commit},
+		{stamp.Dirty, strconv.FormatBool(info.Dirty)},
+		{stamp.Date, info.Date.Format(time.RFC3339)},
+	} {
+		if variable.name != "" {
+			flags = append(flags, "-X="+variable.name+"="+variable.value)
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// Adds the ldflags to the -ldflags already in flags, since go build only uses the last one given.
+func mergeLDFlags(flags []string, ldflags string) []string {
+	flags = slices.Clone(flags)
+	for i, flag := range flags {
+		switch {
+		case (flag == "-ldflags" || flag == "--ldflags") && i+1 < len(flags):
+			flags[i+1] += " " + ldflags
+			return flags
+		case strings.HasPrefix(flag, "-ldflags=") || strings.HasPrefix(flag, "--ldflags="):
+			flags[i] += " " + ldflags
+			return flags
+		}
+	}
+	return append(flags, "-ldflags", ldflags)
+}
@@ -0,0 +1,114 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeLDFlags(t *testing.T) {
+	tests := []struct {
+		Flags    []string
+		Expected []string
+	}{
+		{Flags: nil, Expected: []string{"-ldflags", "-X=main.version=v1"}},
+		{Flags: []string{"-trimpath"}, Expected: []string{"-trimpath", "-ldflags", "-X=main.version=v1"}},
+		{Flags: []string{"-ldflags", "-s -w"}, Expected: []string{"-ldflags", "-s -w -X=main.version=v1"}},
+		{Flags: []string{"-ldflags=-s", "-v"}, Expected: []string{"-ldflags=-s -X=main.version=v1", "-v"}},
+	}
+	for _, test := range tests {
+		original := slices.Clone(test.Flags)
+		if flags := mergeLDFlags(test.Flags, "-X=main.version=v1"); !slices.Equal(flags, test.Expected) {
+			t.Fatalf("got: %q expected: %q", flags, test.Expected)
+		}
+		if !slices.Equal(test.Flags, original) {
+			t.Fatalf("flags were modified: %q", test.Flags)
+		}
+	}
+}
+
+func TestStampLDFlags(t *testing.T) {
+	info := VersionInfo{
+		Version: "v1.0.0-dirty",
+		Commit:  "abc",
+		Dirty:   true,
+		Date:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	stamp := Stamp{Version: "main.version", Dirty: "main.dirty", Date: "example.com/x/build.Date"}
+	expected := "-X=main.version=v1.0.0-dirty -X=main.dirty=true -X=example.com/x/build.Date=2024-01-02T03:04:05Z"
+	if flags := stamp.ldflags(info); flags != expected {
+		t.Fatalf("got: %s expected: %s", flags, expected)
+	}
+}
+
+func TestGitVersion(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	info := GitVersion(context.Background(), dir)
+	if info.Version != DevelVersion || info.Commit != "unknown" || info.Dirty {
+		t.Fatalf("got: %+v expected fallbacks outside of a repository", info)
+	}
+	if !info.Date.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("got: %s expected SOURCE_DATE_EPOCH", info.Date)
+	}
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err.Error(), output)
+		}
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("a"), 0644); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	git("add", "file")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "v1.2.3")
+
+	info = GitVersion(context.Background(), dir)
+	if info.Version != "v1.2.3" || len(info.Commit) != 40 || info.Dirty {
+		t.Fatalf("got: %+v expected a clean v1.2.3", info)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("b"), 0644); err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	info = GitVersion(context.Background(), dir)
+	if info.Version != "v1.2.3-dirty" || !info.Dirty {
+		t.Fatalf("got: %+v expected a dirty v1.2.3", info)
+	}
+}
+
+func TestGoBuildStamp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	dir := writeModule(t, map[string]string{
+		"go.mod":  "module example.com/stamp\n\ngo 1.22\n",
+		"main.go": "package main\n\nimport \"fmt\"\n\nvar version, dirty = \"none\", \"none\"\n\nfunc main() {\n\tfmt.Print(version, \" \", dirty)\n}\n",
+	})
+
+	var stdout strings.Builder
+	build := GoBuild{
+		Output: "stamp",
+		Flags:  []string{"-ldflags", "-s"},
+		Stamp:  Stamp{Version: "main.version", Dirty: "main.dirty"},
+	}
+	if err := build.Run(context.Background(), &Gopher{GoConfig: GoConfig{GoBin: "go"}, Stdout: &stdout}); err != nil {
+		t.Fatalf("got error: %s: %s", err.Error(), stdout.String())
+	}
+	output, err := exec.Command(filepath.Join(dir, "stamp")).Output()
+	if err != nil {
+		t.Fatalf("got error: %s", err.Error())
+	}
+	if string(output) != DevelVersion+" false" {
+		t.Fatalf("got: %q expected: %q", output, DevelVersion+" false")
+	}
+}